@@ -21,6 +21,7 @@ const (
 	ClientName   string = "nutanix-cloud-controller-manager"
 
 	CCMNamespaceKey = "POD_NAMESPACE"
+	CCMPodNameKey   = "POD_NAME"
 
 	InstanceType string = "ahv-vm"
 
@@ -32,5 +33,14 @@ const (
 	CustomHostUUIDLabel string = "nutanix.com/prism-host-uuid"
 	CustomHostNameLabel string = "nutanix.com/prism-host-name"
 
+	DefaultCategoryLabelDomain string = "category.nutanix.com"
+	ManagedLabelsAnnotation    string = "nutanix.com/managed-labels"
+
+	DefaultVMUUIDAnnotation string = "nutanix.com/vm-uuid"
+
 	PrismCentralService string = "PRISM_CENTRAL"
+
+	LoadBalancerIPAMConfigMapName string = "nutanix-loadbalancer-ipam"
+	LoadBalancerIPAnnotation      string = "nutanix.com/load-balancer-ip"
+	LoadBalancerIPPoolAnnotation  string = "nutanix.com/load-balancer-ip-pool"
 )
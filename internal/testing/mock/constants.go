@@ -26,6 +26,12 @@ const (
 	MockDefaultRegion = "region"
 	MockDefaultZone   = "zone"
 
+	MockCategoryTeamKey          = "K8s-Team"
+	MockCategoryTeamValue        = "Data Science/ML"
+	MockCategoryClusterTeamValue = "platform"
+	MockCategoryEnvKey           = "K8s-Env"
+	MockCategoryEnvValue         = "prod"
+
 	MockVMNamePoweredOn                  = "mock-vm-poweredon"
 	MockVMNamePoweredOff                 = "mock-vm-poweredoff"
 	MockVMNameCategories                 = "mock-vm-categories"
@@ -34,16 +40,32 @@ const (
 	MockVMNamePoweredOnClusterCategories = "mock-vm-poweredon-cluster-categories"
 	MockVMNameDpOffload                  = "mock-vm-dp-offload"
 	MockVMNameSecondaryIPs               = "mock-vm-secondary-ips"
+	MockVMNameDualStack                  = "mock-vm-dual-stack"
+	MockVMNameMultiSubnet                = "mock-vm-multi-subnet"
+
+	MockVMNumSockets        = 2
+	MockVMNumCoresPerSocket = 2
+	MockVMMemorySizeBytes   = int64(8 * 1024 * 1024 * 1024)
 
 	MockSecondaryIP1 = "2.2.2.2"
 	MockSecondaryIP2 = "3.3.3.3"
 
+	MockExternalIP = "4.4.4.4"
+
+	MockSubnetName         = "mock-subnet"
+	MockExternalSubnetName = "mock-external-subnet"
+
+	MockIPv6          = "fd00::1"
+	MockIPv6LinkLocal = "fe80::1"
+
+	MockLoadBalancerVIP = "10.10.10.10"
+
+	MockVPCUUID        = "00000000-0000-0000-0000-000000000300"
+	MockRouteTableUUID = "00000000-0000-0000-0000-000000000301"
+
 	MockNodeNameVMNotExisting = "mock-node-no-vm-exists"
 	MockNodeNameNoSystemUUID  = "mock-node-no-system-uuid"
 
-	entityNotFoundError = "ENTITY_NOT_FOUND"
-	vmNotFoundError     = "VM_NOT_FOUND"
-
 	mockHost              = "mock-host"
 	mockAddress           = "mock-address"
 	mockCredentialRef     = "mock-cred"
@@ -65,6 +87,13 @@ const (
 	MockVMPoweredOnClusterCategoriesUUID = "00000000-0000-0000-0000-000000000105"
 	MockVMDpOffloadUUID                  = "00000000-0000-0000-0000-000000000106"
 	MockVMSecondaryIPsUUID               = "00000000-0000-0000-0000-000000000107"
+	MockVMDualStackUUID                  = "00000000-0000-0000-0000-000000000108"
+	MockVMMultiSubnetUUID                = "00000000-0000-0000-0000-000000000109"
 	MockCategoryRegionUUID               = "00000000-0000-0000-0000-000000000200"
 	MockCategoryZoneUUID                 = "00000000-0000-0000-0000-000000000201"
+	MockCategoryTeamUUID                 = "00000000-0000-0000-0000-000000000202"
+	MockCategoryClusterTeamUUID          = "00000000-0000-0000-0000-000000000203"
+	MockCategoryEnvUUID                  = "00000000-0000-0000-0000-000000000204"
+	MockSubnetUUID                       = "00000000-0000-0000-0000-000000000400"
+	MockExternalSubnetUUID               = "00000000-0000-0000-0000-000000000401"
 )
@@ -27,6 +27,7 @@ import (
 	"github.com/onsi/gomega/gstruct"
 
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmCommonModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/common/v1/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
@@ -61,12 +62,16 @@ func getDefaultVM(vmName string, vmUUID string, cluster *clusterModels.Cluster,
 		fmt.Printf("error setting nic network info: %+v\n", err)
 		return nil
 	}
+	nic.ExtId = ptr.To(getDefaultNicUUID(vmUUID))
 
 	vm := &vmmModels.Vm{
-		ExtId:      ptr.To(vmUUID),
-		Categories: make([]vmmModels.CategoryReference, 0),
-		PowerState: vmmModels.POWERSTATE_ON.Ref(),
-		Name:       ptr.To(vmName),
+		ExtId:             ptr.To(vmUUID),
+		Categories:        make([]vmmModels.CategoryReference, 0),
+		PowerState:        vmmModels.POWERSTATE_ON.Ref(),
+		Name:              ptr.To(vmName),
+		NumSockets:        ptr.To(MockVMNumSockets),
+		NumCoresPerSocket: ptr.To(MockVMNumCoresPerSocket),
+		MemorySizeBytes:   ptr.To(MockVMMemorySizeBytes),
 		Cluster: &vmmModels.ClusterReference{
 			ExtId: cluster.ExtId,
 		},
@@ -103,12 +108,16 @@ func getDefaultVMWithDpOffload(vmName string, vmUUID string, cluster *clusterMod
 		fmt.Printf("error setting nic network info: %+v\n", err)
 		return nil
 	}
+	nic.ExtId = ptr.To(getDefaultNicUUID(vmUUID))
 
 	vm := &vmmModels.Vm{
-		ExtId:      ptr.To(vmUUID),
-		Categories: make([]vmmModels.CategoryReference, 0),
-		PowerState: vmmModels.POWERSTATE_ON.Ref(),
-		Name:       ptr.To(vmName),
+		ExtId:             ptr.To(vmUUID),
+		Categories:        make([]vmmModels.CategoryReference, 0),
+		PowerState:        vmmModels.POWERSTATE_ON.Ref(),
+		Name:              ptr.To(vmName),
+		NumSockets:        ptr.To(MockVMNumSockets),
+		NumCoresPerSocket: ptr.To(MockVMNumCoresPerSocket),
+		MemorySizeBytes:   ptr.To(MockVMMemorySizeBytes),
 		Cluster: &vmmModels.ClusterReference{
 			ExtId: cluster.ExtId,
 		},
@@ -154,12 +163,16 @@ func getDefaultVMWithSecondaryIPs(vmName string, vmUUID string, cluster *cluster
 		fmt.Printf("error setting nic network info: %+v\n", err)
 		return nil
 	}
+	nic.ExtId = ptr.To(getDefaultNicUUID(vmUUID))
 
 	vm := &vmmModels.Vm{
-		ExtId:      ptr.To(vmUUID),
-		Categories: make([]vmmModels.CategoryReference, 0),
-		PowerState: vmmModels.POWERSTATE_ON.Ref(),
-		Name:       ptr.To(vmName),
+		ExtId:             ptr.To(vmUUID),
+		Categories:        make([]vmmModels.CategoryReference, 0),
+		PowerState:        vmmModels.POWERSTATE_ON.Ref(),
+		Name:              ptr.To(vmName),
+		NumSockets:        ptr.To(MockVMNumSockets),
+		NumCoresPerSocket: ptr.To(MockVMNumCoresPerSocket),
+		MemorySizeBytes:   ptr.To(MockVMMemorySizeBytes),
 		Cluster: &vmmModels.ClusterReference{
 			ExtId: cluster.ExtId,
 		},
@@ -175,6 +188,92 @@ func getDefaultVMWithSecondaryIPs(vmName string, vmUUID string, cluster *cluster
 	return vm
 }
 
+func getDefaultVMWithDualStack(vmName string, vmUUID string, cluster *clusterModels.Cluster, host *clusterModels.Host) *vmmModels.Vm {
+	nic := vmmModels.NewNic()
+	nicNetInfo := vmmModels.NewVirtualEthernetNicNetworkInfo()
+
+	nicNetInfo.Ipv4Config = vmmModels.NewIpv4Config()
+	nicNetInfo.Ipv4Config.IpAddress = &vmmCommonModels.IPv4Address{
+		Value: ptr.To(MockIP),
+	}
+
+	nicNetInfo.Ipv6Info = vmmModels.NewIpv6Info()
+	nicNetInfo.Ipv6Info.LearnedIpv6Addresses = []vmmCommonModels.IPv6Address{
+		{
+			Value: ptr.To(MockIPv6),
+		},
+		{
+			Value: ptr.To(MockIPv6LinkLocal),
+		},
+	}
+
+	err := nic.SetNicNetworkInfo(*nicNetInfo)
+	if err != nil {
+		fmt.Printf("error setting nic network info: %+v\n", err)
+		return nil
+	}
+	nic.ExtId = ptr.To(getDefaultNicUUID(vmUUID))
+
+	vm := &vmmModels.Vm{
+		ExtId:             ptr.To(vmUUID),
+		Categories:        make([]vmmModels.CategoryReference, 0),
+		PowerState:        vmmModels.POWERSTATE_ON.Ref(),
+		Name:              ptr.To(vmName),
+		NumSockets:        ptr.To(MockVMNumSockets),
+		NumCoresPerSocket: ptr.To(MockVMNumCoresPerSocket),
+		MemorySizeBytes:   ptr.To(MockVMMemorySizeBytes),
+		Cluster: &vmmModels.ClusterReference{
+			ExtId: cluster.ExtId,
+		},
+		Nics: []vmmModels.Nic{
+			*nic,
+		},
+	}
+	if host != nil {
+		vm.Host = &vmmModels.HostReference{
+			ExtId: host.ExtId,
+		}
+	}
+	return vm
+}
+
+// getDefaultVMWithMultipleSubnets returns a VM with one NIC in the internal mock subnet and one
+// NIC in the external mock subnet.
+func getDefaultVMWithMultipleSubnets(vmName string, vmUUID string, cluster *clusterModels.Cluster, host *clusterModels.Host) *vmmModels.Vm {
+	vm := getDefaultVM(vmName, vmUUID, cluster, host)
+	nics := make([]vmmModels.Nic, 0)
+	for i, nicConfig := range []struct{ ip, subnetUUID string }{
+		{MockIP, MockSubnetUUID},
+		{MockExternalIP, MockExternalSubnetUUID},
+	} {
+		nic := vmmModels.NewNic()
+		nicNetInfo := vmmModels.NewVirtualEthernetNicNetworkInfo()
+		nicNetInfo.Ipv4Config = vmmModels.NewIpv4Config()
+		nicNetInfo.Ipv4Config.IpAddress = &vmmCommonModels.IPv4Address{
+			Value: ptr.To(nicConfig.ip),
+		}
+		nicNetInfo.Subnet = &vmmModels.SubnetReference{
+			ExtId: ptr.To(nicConfig.subnetUUID),
+		}
+		err := nic.SetNicNetworkInfo(*nicNetInfo)
+		if err != nil {
+			fmt.Printf("error setting nic network info: %+v\n", err)
+			return nil
+		}
+		nic.ExtId = ptr.To(fmt.Sprintf("%s-%d", getDefaultNicUUID(vmUUID), i))
+		nics = append(nics, *nic)
+	}
+	vm.Nics = nics
+	return vm
+}
+
+func getDefaultSubnet(subnetName string, subnetUUID string) *networkingModels.Subnet {
+	subnet := networkingModels.NewSubnet()
+	subnet.ExtId = ptr.To(subnetUUID)
+	subnet.Name = ptr.To(subnetName)
+	return subnet
+}
+
 func getDefaultCluster(clusterName string, clusterUUID string) *clusterModels.Cluster {
 	cluster := clusterModels.NewCluster()
 	cluster.ExtId = ptr.To(clusterUUID)
@@ -274,3 +373,12 @@ func CreatePrismCentralCluster(clusterName string, clusterUUID string) *clusterM
 	}
 	return pc
 }
+
+func CreateHost(hostName string, hostUUID string, clusterUUID string) *clusterModels.Host {
+	return getDefaultHost(hostName, hostUUID, clusterUUID)
+}
+
+// getDefaultNicUUID returns the ExtId of the NIC of VMs created by the default helpers
+func getDefaultNicUUID(vmUUID string) string {
+	return vmUUID + "-nic"
+}
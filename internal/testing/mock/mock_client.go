@@ -17,6 +17,8 @@ limitations under the License.
 package mock
 
 import (
+	"fmt"
+
 	"k8s.io/client-go/informers"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
@@ -24,22 +26,45 @@ import (
 
 // MockClient is a mock implementation of the interfaces.Client interface
 type MockClient struct {
-	mockPrism       MockPrism
+	// prismCentrals are the names of the mock Prism Centrals in order, the first one is unnamed
+	prismCentrals   []string
+	mockPrisms      map[string]*MockPrism
 	sharedInformers informers.SharedInformerFactory
 }
 
 // CreateMockClient creates a new MockClient
 func CreateMockClient(mockEnvironment MockEnvironment) *MockClient {
 	return &MockClient{
-		mockPrism: MockPrism{
-			mockEnvironment: mockEnvironment,
+		prismCentrals: []string{""},
+		mockPrisms: map[string]*MockPrism{
+			"": {mockEnvironment: mockEnvironment},
 		},
 	}
 }
 
-// Get returns the mockPrism
+// AddPrismCentral adds a Prism Central managing the entities of mockEnvironment
+func (mc *MockClient) AddPrismCentral(name string, mockEnvironment MockEnvironment) {
+	mc.prismCentrals = append(mc.prismCentrals, name)
+	mc.mockPrisms[name] = &MockPrism{mockEnvironment: mockEnvironment}
+}
+
+// Get returns the mockPrism of the first Prism Central
 func (mc *MockClient) Get() (interfaces.Prism, error) {
-	return &mc.mockPrism, nil
+	return mc.GetForPrismCentral(mc.prismCentrals[0])
+}
+
+// GetForPrismCentral returns the mockPrism of the named Prism Central
+func (mc *MockClient) GetForPrismCentral(name string) (interfaces.Prism, error) {
+	mockPrism, ok := mc.mockPrisms[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown Prism Central %q", name)
+	}
+	return mockPrism, nil
+}
+
+// PrismCentrals returns the names of the Prism Centrals
+func (mc *MockClient) PrismCentrals() []string {
+	return mc.prismCentrals
 }
 
 // SetInformers sets the sharedInformers
@@ -21,6 +21,7 @@ import (
 	"context"
 
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmCommonModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/common/v1/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
@@ -38,6 +39,11 @@ type MockEnvironment struct {
 	managedMockCategories map[string]*prismModels.Category
 	managedNodes          map[string]*v1.Node
 	vmNameToExtId         map[string]string
+
+	managedMockLoadBalancerSessions map[string]*networkingModels.LoadBalancerSession
+	managedMockRouteTables          map[string]*networkingModels.RouteTable
+	managedMockRoutes               map[string]*networkingModels.Route
+	managedMockSubnets              map[string]*networkingModels.Subnet
 }
 
 func (m *MockEnvironment) GetVM(ctx context.Context, vmName string) *vmmModels.Vm {
@@ -63,12 +69,65 @@ func (m *MockEnvironment) GetCluster(ctx context.Context, clusterName string) *c
 	return nil
 }
 
+// GetLoadBalancerSessions returns the load balancer sessions created through the mock client
+func (m *MockEnvironment) GetLoadBalancerSessions() []*networkingModels.LoadBalancerSession {
+	sessions := make([]*networkingModels.LoadBalancerSession, 0, len(m.managedMockLoadBalancerSessions))
+	for _, s := range m.managedMockLoadBalancerSessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// AddLoadBalancerSession adds a load balancer session as if it was created outside of the mock client
+func (m *MockEnvironment) AddLoadBalancerSession(session *networkingModels.LoadBalancerSession) {
+	Expect(session.ExtId).ToNot(BeNil()) // nolint:typecheck
+	m.managedMockLoadBalancerSessions[*session.ExtId] = session
+}
+
+// GetRoutes returns the routes created through the mock client
+func (m *MockEnvironment) GetRoutes() []*networkingModels.Route {
+	routes := make([]*networkingModels.Route, 0, len(m.managedMockRoutes))
+	for _, r := range m.managedMockRoutes {
+		routes = append(routes, r)
+	}
+	return routes
+}
+
+// AddRoute adds a route as if it was created outside of the mock client
+func (m *MockEnvironment) AddRoute(route *networkingModels.Route) {
+	Expect(route.ExtId).ToNot(BeNil()) // nolint:typecheck
+	m.managedMockRoutes[*route.ExtId] = route
+}
+
 func (m *MockEnvironment) AddCluster(cluster *clusterModels.Cluster) *clusterModels.Cluster {
 	Expect(cluster).ToNot(BeNil()) // nolint:typecheck
 	m.managedMockClusters[*cluster.ExtId] = cluster
 	return nil
 }
 
+func (m *MockEnvironment) AddHost(host *clusterModels.Host) {
+	Expect(host).ToNot(BeNil()) // nolint:typecheck
+	m.managedMockHosts[*host.ExtId] = host
+}
+
+// AssociateHostCategories associates the categories with the host
+func (m *MockEnvironment) AssociateHostCategories(hostUUID string, categoryUUIDs ...string) {
+	for _, categoryUUID := range categoryUUIDs {
+		category, ok := m.managedMockCategories[categoryUUID]
+		Expect(ok).To(BeTrue()) // nolint:typecheck
+		category.DetailedAssociations = append(category.DetailedAssociations, prismModels.AssociationDetail{
+			CategoryId:   ptr.To(categoryUUID),
+			ResourceId:   ptr.To(hostUUID),
+			ResourceType: prismModels.RESOURCETYPE_HOST.Ref(),
+		})
+	}
+}
+
+func (m *MockEnvironment) DeleteVM(vmUUID string) {
+	Expect(vmUUID).ToNot(BeEmpty()) // nolint:typecheck
+	delete(m.managedMockMachines, vmUUID)
+}
+
 func (m *MockEnvironment) DeleteCluster(clusterUUID string) {
 	Expect(clusterUUID).ToNot(BeEmpty()) // nolint:typecheck
 	delete(m.managedMockClusters, clusterUUID)
@@ -86,6 +145,10 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 	// Create categories with consistent UUIDs
 	regionCategory := getDefaultCategory(MockDefaultRegion, MockCategoryRegionUUID, MockRegion)
 	zoneCategory := getDefaultCategory(MockDefaultZone, MockCategoryZoneUUID, MockZone)
+	teamCategory := getDefaultCategory(MockCategoryTeamKey, MockCategoryTeamUUID, MockCategoryTeamValue)
+	clusterTeamCategory := getDefaultCategory(MockCategoryTeamKey, MockCategoryClusterTeamUUID, MockCategoryClusterTeamValue)
+	envCategory := getDefaultCategory(MockCategoryEnvKey, MockCategoryEnvUUID, MockCategoryEnvValue)
+	clusterCategories.Categories = []string{*clusterTeamCategory.ExtId, *envCategory.ExtId}
 
 	// Create VMs with consistent UUIDs
 	poweredOnVM := getDefaultVM(MockVMNamePoweredOn, MockVMPoweredOnUUID, cluster, host)
@@ -124,7 +187,7 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 	filteredAddressesVM := getDefaultVM(MockVMNameFilteredNodeAddresses, MockVMFilteredAddressesUUID, cluster, host)
 	// Create multiple NICs with different IPs
 	filteredNics := make([]vmmModels.Nic, 0)
-	ipAddresses := []string{"127.100.10.1", "127.200.20.1", "127.200.100.64", "127.200.200.10", MockIP}
+	ipAddresses := []string{"10.100.10.1", "10.200.20.1", "10.200.100.64", "10.200.200.10", MockIP}
 	for _, ip := range ipAddresses {
 		nic := vmmModels.NewNic()
 		nicNetInfo := vmmModels.NewVirtualEthernetNicNetworkInfo()
@@ -163,6 +226,7 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 	poweredOnVMClusterCategories.Categories = []vmmModels.CategoryReference{
 		{ExtId: regionCategory.ExtId},
 		{ExtId: zoneCategory.ExtId},
+		{ExtId: teamCategory.ExtId},
 	}
 	poweredOnClusterCategoriesNode, err := createNodeForVM(ctx, kClient, poweredOnVMClusterCategories)
 	if err != nil {
@@ -181,6 +245,18 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 		return nil, err
 	}
 
+	dualStackVM := getDefaultVMWithDualStack(MockVMNameDualStack, MockVMDualStackUUID, cluster, host)
+	dualStackNode, err := createNodeForVM(ctx, kClient, dualStackVM)
+	if err != nil {
+		return nil, err
+	}
+
+	multiSubnetVM := getDefaultVMWithMultipleSubnets(MockVMNameMultiSubnet, MockVMMultiSubnetUUID, cluster, host)
+	multiSubnetNode, err := createNodeForVM(ctx, kClient, multiSubnetVM)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MockEnvironment{
 		managedMockMachines: map[string]*vmmModels.Vm{
 			*poweredOnVM.ExtId:                  poweredOnVM,
@@ -191,6 +267,8 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			*filteredAddressesVM.ExtId:          filteredAddressesVM,
 			*dpOffloadVM.ExtId:                  dpOffloadVM,
 			*secondaryIPsVM.ExtId:               secondaryIPsVM,
+			*dualStackVM.ExtId:                  dualStackVM,
+			*multiSubnetVM.ExtId:                multiSubnetVM,
 		},
 		managedMockClusters: map[string]*clusterModels.Cluster{
 			*cluster.ExtId:           cluster,
@@ -201,8 +279,11 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			*host.ExtId: host,
 		},
 		managedMockCategories: map[string]*prismModels.Category{
-			*regionCategory.ExtId: regionCategory,
-			*zoneCategory.ExtId:   zoneCategory,
+			*regionCategory.ExtId:      regionCategory,
+			*zoneCategory.ExtId:        zoneCategory,
+			*teamCategory.ExtId:        teamCategory,
+			*clusterTeamCategory.ExtId: clusterTeamCategory,
+			*envCategory.ExtId:         envCategory,
 		},
 		managedNodes: map[string]*v1.Node{
 			MockVMNamePoweredOn:                  poweredOnNode,
@@ -215,6 +296,8 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			MockVMNameFilteredNodeAddresses:      filteredAddressesNode,
 			MockVMNameDpOffload:                  dpOffloadNode,
 			MockVMNameSecondaryIPs:               secondaryIPsNode,
+			MockVMNameDualStack:                  dualStackNode,
+			MockVMNameMultiSubnet:                multiSubnetNode,
 		},
 		vmNameToExtId: map[string]string{
 			MockVMNamePoweredOn:                  *poweredOnVM.ExtId,
@@ -225,6 +308,20 @@ func CreateMockEnvironment(ctx context.Context, kClient *fake.Clientset) (*MockE
 			MockVMNamePoweredOnClusterCategories: *poweredOnVMClusterCategories.ExtId,
 			MockVMNameDpOffload:                  *dpOffloadVM.ExtId,
 			MockVMNameSecondaryIPs:               *secondaryIPsVM.ExtId,
+			MockVMNameDualStack:                  *dualStackVM.ExtId,
+			MockVMNameMultiSubnet:                *multiSubnetVM.ExtId,
+		},
+		managedMockLoadBalancerSessions: map[string]*networkingModels.LoadBalancerSession{},
+		managedMockRouteTables: map[string]*networkingModels.RouteTable{
+			MockRouteTableUUID: {
+				ExtId:        ptr.To(MockRouteTableUUID),
+				VpcReference: ptr.To(MockVPCUUID),
+			},
+		},
+		managedMockRoutes: map[string]*networkingModels.Route{},
+		managedMockSubnets: map[string]*networkingModels.Subnet{
+			MockSubnetUUID:         getDefaultSubnet(MockSubnetName, MockSubnetUUID),
+			MockExternalSubnetUUID: getDefaultSubnet(MockExternalSubnetName, MockExternalSubnetUUID),
 		},
 	}, nil
 }
@@ -20,9 +20,15 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingCommonModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/common/v1/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
 )
 
 type MockPrism struct {
@@ -33,10 +39,19 @@ func (mp *MockPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, e
 	if v, ok := mp.mockEnvironment.managedMockMachines[vmUUID]; ok {
 		return v, nil
 	} else {
-		return nil, fmt.Errorf(vmNotFoundError)
+		return nil, fmt.Errorf("%w: vm %s", interfaces.ErrNotFound, vmUUID)
 	}
 }
 
+func (mp *MockPrism) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	entities := make([]vmmModels.Vm, 0)
+
+	for _, e := range mp.mockEnvironment.managedMockMachines {
+		entities = append(entities, *e)
+	}
+	return entities, nil
+}
+
 func (mp *MockPrism) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
 	return mp.mockEnvironment.managedMockClusters[clusterUUID], nil
 }
@@ -54,12 +69,117 @@ func (mp *MockPrism) GetCategory(ctx context.Context, categoryUUID string) (*pri
 	if cat, ok := mp.mockEnvironment.managedMockCategories[categoryUUID]; ok {
 		return cat, nil
 	}
-	return nil, fmt.Errorf(entityNotFoundError)
+	return nil, fmt.Errorf("%w: category %s", interfaces.ErrNotFound, categoryUUID)
+}
+
+func (mp *MockPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	entities := make([]prismModels.Category, 0)
+
+	for _, e := range mp.mockEnvironment.managedMockCategories {
+		entities = append(entities, *e)
+	}
+	return entities, nil
 }
 
 func (mp *MockPrism) GetClusterHost(ctx context.Context, clusterUuid string, hostUUID string) (*clusterModels.Host, error) {
 	if host, ok := mp.mockEnvironment.managedMockHosts[hostUUID]; ok {
 		return host, nil
 	}
-	return nil, fmt.Errorf(entityNotFoundError)
+	return nil, fmt.Errorf("%w: host %s", interfaces.ErrNotFound, hostUUID)
+}
+
+func (mp *MockPrism) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	if subnet, ok := mp.mockEnvironment.managedMockSubnets[subnetUUID]; ok {
+		return subnet, nil
+	}
+	return nil, fmt.Errorf("%w: subnet %s", interfaces.ErrNotFound, subnetUUID)
+}
+
+func (mp *MockPrism) ListLoadBalancerSessions(ctx context.Context, filter string) ([]networkingModels.LoadBalancerSession, error) {
+	entities := make([]networkingModels.LoadBalancerSession, 0)
+
+	for _, e := range mp.mockEnvironment.managedMockLoadBalancerSessions {
+		entities = append(entities, *e)
+	}
+	return entities, nil
+}
+
+func (mp *MockPrism) CreateLoadBalancerSession(ctx context.Context, session *networkingModels.LoadBalancerSession) error {
+	created := *session
+	created.ExtId = ptr.To(uuid.NewString())
+	// Emulate Prism assigning a virtual IP from the subnet
+	if created.Listener != nil && created.Listener.VirtualIP != nil &&
+		created.Listener.VirtualIP.AssignmentType != nil &&
+		*created.Listener.VirtualIP.AssignmentType == networkingModels.ASSIGNMENTTYPE_DYNAMIC {
+		vip := *created.Listener.VirtualIP
+		vip.IpAddress = &networkingCommonModels.IPAddress{
+			Ipv4: &networkingCommonModels.IPv4Address{Value: ptr.To(MockLoadBalancerVIP)},
+		}
+		listener := *created.Listener
+		listener.VirtualIP = &vip
+		created.Listener = &listener
+	}
+	mp.mockEnvironment.managedMockLoadBalancerSessions[*created.ExtId] = &created
+	return nil
+}
+
+func (mp *MockPrism) UpdateLoadBalancerSession(ctx context.Context, sessionUUID string, session *networkingModels.LoadBalancerSession) error {
+	if _, ok := mp.mockEnvironment.managedMockLoadBalancerSessions[sessionUUID]; !ok {
+		return fmt.Errorf("%w: load balancer session %s", interfaces.ErrNotFound, sessionUUID)
+	}
+	updated := *session
+	updated.ExtId = ptr.To(sessionUUID)
+	mp.mockEnvironment.managedMockLoadBalancerSessions[sessionUUID] = &updated
+	return nil
+}
+
+func (mp *MockPrism) DeleteLoadBalancerSession(ctx context.Context, sessionUUID string) error {
+	if _, ok := mp.mockEnvironment.managedMockLoadBalancerSessions[sessionUUID]; !ok {
+		return fmt.Errorf("%w: load balancer session %s", interfaces.ErrNotFound, sessionUUID)
+	}
+	delete(mp.mockEnvironment.managedMockLoadBalancerSessions, sessionUUID)
+	return nil
+}
+
+func (mp *MockPrism) ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error) {
+	entities := make([]networkingModels.RouteTable, 0)
+
+	for _, e := range mp.mockEnvironment.managedMockRouteTables {
+		entities = append(entities, *e)
+	}
+	return entities, nil
+}
+
+func (mp *MockPrism) ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error) {
+	if _, ok := mp.mockEnvironment.managedMockRouteTables[routeTableUUID]; !ok {
+		return nil, fmt.Errorf("%w: route table %s", interfaces.ErrNotFound, routeTableUUID)
+	}
+	entities := make([]networkingModels.Route, 0)
+
+	for _, e := range mp.mockEnvironment.managedMockRoutes {
+		if e.RouteTableReference != nil && *e.RouteTableReference == routeTableUUID {
+			entities = append(entities, *e)
+		}
+	}
+	return entities, nil
+}
+
+func (mp *MockPrism) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error {
+	if _, ok := mp.mockEnvironment.managedMockRouteTables[routeTableUUID]; !ok {
+		return fmt.Errorf("%w: route table %s", interfaces.ErrNotFound, routeTableUUID)
+	}
+	created := *route
+	created.ExtId = ptr.To(uuid.NewString())
+	created.RouteTableReference = ptr.To(routeTableUUID)
+	mp.mockEnvironment.managedMockRoutes[*created.ExtId] = &created
+	return nil
+}
+
+func (mp *MockPrism) DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error {
+	r, ok := mp.mockEnvironment.managedMockRoutes[routeUUID]
+	if !ok || r.RouteTableReference == nil || *r.RouteTableReference != routeTableUUID {
+		return fmt.Errorf("%w: route %s", interfaces.ErrNotFound, routeUUID)
+	}
+	delete(mp.mockEnvironment.managedMockRoutes, routeUUID)
+	return nil
 }
@@ -25,6 +25,7 @@ import (
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/cloud-provider/app"
 	"k8s.io/cloud-provider/app/config"
+	"k8s.io/cloud-provider/names"
 	"k8s.io/cloud-provider/options"
 	"k8s.io/component-base/cli"
 	cliflag "k8s.io/component-base/cli/flag"
@@ -33,7 +34,7 @@ import (
 	klog "k8s.io/klog/v2"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
-	_ "github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider"
 )
 
 func main() {
@@ -44,10 +45,23 @@ func main() {
 	ccmOptions.KubeCloudShared.CloudProvider.Name = constants.ProviderName
 
 	fss := cliflag.NamedFlagSets{}
+	provider.AddTracingFlags(fss.FlagSet("tracing"))
 
 	controllerInitializers := app.DefaultInitFuncConstructors
-	delete(controllerInitializers, "service")
-	delete(controllerInitializers, "route")
+	for name, constructor := range map[string]app.InitFuncConstructor{
+		names.ServiceLBController: provider.StartServiceControllerWrapper,
+		names.NodeRouteController: provider.StartRouteControllerWrapper,
+	} {
+		initializer := controllerInitializers[name]
+		initializer.Constructor = constructor
+		controllerInitializers[name] = initializer
+	}
+	controllerInitializers[provider.NodeLabelControllerName] = app.ControllerInitFuncConstructor{
+		InitContext: app.ControllerInitContext{
+			ClientName: provider.NodeLabelControllerName,
+		},
+		Constructor: provider.StartNodeLabelControllerWrapper,
+	}
 
 	command := app.NewCloudControllerManagerCommand(ccmOptions,
 		cloudInitializer, controllerInitializers, map[string]string{}, fss, wait.NeverStop)
@@ -68,6 +82,10 @@ func cloudInitializer(config *config.CompletedConfig) cloudprovider.Interface {
 		klog.Fatalf("Cloud provider is nil")
 	}
 
+	if ntnxCloud, ok := cloud.(*provider.NtnxCloud); ok && cloudConfig.CloudConfigFile != "" {
+		ntnxCloud.WatchConfigFile(cloudConfig.CloudConfigFile)
+	}
+
 	if !cloud.HasClusterID() {
 		if config.ComponentConfig.KubeCloudShared.AllowUntaggedCloud {
 			klog.Warning("detected a cluster without a ClusterID.  A ClusterID will be required in the future.  Please tag your cluster to avoid any future issues")
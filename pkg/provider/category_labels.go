@@ -0,0 +1,141 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var invalidLabelCharacters = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// getCategoryLabels returns the node labels for the selected categories of the VM, the host running
+// it and its cluster. Categories of the VM override categories of the host, which override
+// categories of the cluster with the same key. Keys with multiple values on the same entity are
+// skipped, as a label can only hold one value.
+func (n *nutanixManager) getCategoryLabels(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, host *clusterModels.Host, cluster *clusterModels.Cluster) (map[string]string, error) {
+	categoryLabels := n.getConfig().CategoryLabels
+	if categoryLabels == nil {
+		return nil, nil
+	}
+	domain := categoryLabels.Domain
+	if domain == "" {
+		domain = constants.DefaultCategoryLabelDomain
+	}
+
+	var clusterCategoryUUIDs []string
+	if cluster != nil {
+		clusterCategoryUUIDs = cluster.Categories
+	}
+	var hostCategoryUUIDs []string
+	if host != nil && host.ExtId != nil {
+		var err error
+		hostCategoryUUIDs, err = getHostCategoryUUIDs(ctx, nClient, *host.ExtId, categoryKeyFilters(categoryLabels))
+		if err != nil {
+			return nil, err
+		}
+	}
+	vmCategoryUUIDs := make([]string, 0, len(vm.Categories))
+	for _, category := range vm.Categories {
+		if category.ExtId != nil {
+			vmCategoryUUIDs = append(vmCategoryUUIDs, *category.ExtId)
+		}
+	}
+
+	labels := map[string]string{}
+	for _, categoryUUIDs := range [][]string{clusterCategoryUUIDs, hostCategoryUUIDs, vmCategoryUUIDs} {
+		categories, err := n.getSelectedCategories(ctx, nClient, categoryUUIDs)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range categories {
+			if len(values) != 1 {
+				klog.Warningf("skipping category %s with multiple values %v for node labels", key, values) //nolint:typecheck
+				continue
+			}
+			labelKey := fmt.Sprintf("%s/%s", domain, sanitizeLabelValue(key))
+			if errs := validation.IsQualifiedName(labelKey); len(errs) > 0 {
+				klog.Warningf("skipping category %s with invalid label key %q: %s", key, labelKey, strings.Join(errs, ", ")) //nolint:typecheck
+				continue
+			}
+			labels[labelKey] = sanitizeLabelValue(values[0])
+		}
+	}
+	return labels, nil
+}
+
+// getSelectedCategories returns the values of the categories selected by the category labels config by key.
+func (n *nutanixManager) getSelectedCategories(ctx context.Context, nClient interfaces.Prism, categoryUUIDs []string) (map[string][]string, error) {
+	categories := make(map[string][]string)
+	for _, categoryUUID := range categoryUUIDs {
+		category, err := nClient.GetCategory(ctx, categoryUUID)
+		if err != nil {
+			return nil, err
+		}
+		if category == nil || category.Key == nil || category.Value == nil || !n.isCategoryKeySelected(*category.Key) {
+			continue
+		}
+		if !slices.Contains(categories[*category.Key], *category.Value) {
+			categories[*category.Key] = append(categories[*category.Key], *category.Value)
+		}
+	}
+	return categories, nil
+}
+
+// categoryKeyFilters returns the filters of the category keys selected by the category labels config.
+func categoryKeyFilters(categoryLabels *config.CategoryLabels) []string {
+	keyFilters := make([]string, 0, len(categoryLabels.Keys)+len(categoryLabels.KeyPrefixes))
+	for _, key := range categoryLabels.Keys {
+		keyFilters = append(keyFilters, fmt.Sprintf("key eq '%s'", escapeODataString(key)))
+	}
+	for _, prefix := range categoryLabels.KeyPrefixes {
+		keyFilters = append(keyFilters, fmt.Sprintf("startswith(key, '%s')", escapeODataString(prefix)))
+	}
+	return keyFilters
+}
+
+func (n *nutanixManager) isCategoryKeySelected(key string) bool {
+	categoryLabels := n.getConfig().CategoryLabels
+	if slices.Contains(categoryLabels.Keys, key) {
+		return true
+	}
+	return slices.ContainsFunc(categoryLabels.KeyPrefixes, func(prefix string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+// sanitizeLabelValue replaces characters not allowed in label values and label key names with
+// dashes, and truncates the value to the maximum label value length.
+func sanitizeLabelValue(value string) string {
+	value = invalidLabelCharacters.ReplaceAllString(value, "-")
+	if len(value) > validation.LabelValueMaxLength {
+		value = value[:validation.LabelValueMaxLength]
+	}
+	return strings.Trim(value, "-_.")
+}
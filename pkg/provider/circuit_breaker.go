@@ -0,0 +1,196 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+const (
+	defaultCircuitBreakerFailureThreshold = 5
+	defaultCircuitBreakerOpenDuration     = 30 * time.Second
+
+	prismCircuitOpenedReason = "PrismCircuitOpened"
+	prismCircuitClosedReason = "PrismCircuitClosed"
+)
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitHalfOpen
+	circuitOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "open"
+	}
+}
+
+// circuitBreaker suspends requests to a Prism Central after consecutive requests failed with
+// interfaces.ErrUnavailable. Once the open duration passed, a single probe request is let through
+// and its outcome closes or reopens the circuit.
+type circuitBreaker struct {
+	// prismCentral is the name of the Prism Central, empty if it is the only one
+	prismCentral     string
+	failureThreshold int
+	openDuration     time.Duration
+	clock            clock.PassiveClock
+
+	mu    sync.Mutex
+	state circuitState
+	// generation is incremented on every state change, results of requests allowed in an earlier
+	// generation are ignored
+	generation uint64
+	failures   int
+	probing    bool
+	openedAt   time.Time
+
+	recorder record.EventRecorder
+	pod      *v1.ObjectReference
+}
+
+// newCircuitBreaker returns the circuit breaker of the named Prism Central, or nil if the circuit
+// breaker is disabled.
+func newCircuitBreaker(breakerConfig config.PrismCircuitBreaker, prismCentral string, clock clock.PassiveClock) *circuitBreaker {
+	if breakerConfig.Disabled {
+		return nil
+	}
+	failureThreshold := breakerConfig.FailureThreshold
+	if failureThreshold == 0 {
+		failureThreshold = defaultCircuitBreakerFailureThreshold
+	}
+	prismCircuitBreakerState.WithLabelValues(prismCentral).Set(float64(circuitClosed))
+	return &circuitBreaker{
+		prismCentral:     prismCentral,
+		failureThreshold: failureThreshold,
+		openDuration:     durationOrDefault(breakerConfig.OpenDuration.Duration, defaultCircuitBreakerOpenDuration),
+		clock:            clock,
+	}
+}
+
+// setEventRecorder makes the circuit breaker record state changes as events of the given pod.
+func (b *circuitBreaker) setEventRecorder(recorder record.EventRecorder, pod *v1.ObjectReference) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.recorder = recorder
+	b.pod = pod
+}
+
+// circuitTicket identifies an allowed request when its result is recorded.
+type circuitTicket struct {
+	generation uint64
+	probe      bool
+}
+
+// allow returns interfaces.ErrCircuitOpen if the request must not be sent. Every allowed request must
+// be followed by a call to done with the returned ticket and its result.
+func (b *circuitBreaker) allow() (circuitTicket, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		remaining := b.openDuration - b.clock.Since(b.openedAt)
+		if remaining > 0 {
+			return circuitTicket{}, fmt.Errorf("%w for %s", interfaces.ErrCircuitOpen, remaining.Round(time.Second))
+		}
+		b.setState(circuitHalfOpen, nil)
+		b.probing = true
+		return circuitTicket{generation: b.generation, probe: true}, nil
+	case circuitHalfOpen:
+		if b.probing {
+			return circuitTicket{}, fmt.Errorf("%w until the probe request completes", interfaces.ErrCircuitOpen)
+		}
+		b.probing = true
+		return circuitTicket{generation: b.generation, probe: true}, nil
+	}
+	return circuitTicket{generation: b.generation}, nil
+}
+
+// done records the result of an allowed request. Only the probe changes the state of an open
+// circuit, results of requests allowed before the last state change are ignored, so that a slow
+// request sent before the circuit opened cannot close it again.
+func (b *circuitBreaker) done(ticket circuitTicket, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.generation != b.generation {
+		return
+	}
+	if ticket.probe {
+		b.probing = false
+	}
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		// The request tells nothing about the availability of Prism Central
+	case errors.Is(err, interfaces.ErrUnavailable):
+		b.failures++
+		if ticket.probe || (b.state == circuitClosed && b.failures >= b.failureThreshold) {
+			b.openedAt = b.clock.Now()
+			b.setState(circuitOpen, err)
+		}
+	default:
+		b.failures = 0
+		if ticket.probe {
+			b.setState(circuitClosed, nil)
+		}
+	}
+}
+
+func (b *circuitBreaker) setState(state circuitState, err error) {
+	klog.Infof("%s circuit breaker changed from %s to %s", b.target(), b.state, state) //nolint:typecheck
+	b.state = state
+	b.generation++
+	prismCircuitBreakerState.WithLabelValues(b.prismCentral).Set(float64(state))
+	if b.recorder == nil {
+		return
+	}
+	switch state {
+	case circuitOpen:
+		b.recorder.Eventf(b.pod, v1.EventTypeWarning, prismCircuitOpenedReason,
+			"Suspending requests to %s for %s after %d consecutive failures: %v", b.target(), b.openDuration, b.failures, err)
+	case circuitClosed:
+		b.recorder.Eventf(b.pod, v1.EventTypeNormal, prismCircuitClosedReason, "Resumed requests to %s", b.target())
+	}
+}
+
+// target names the Prism Central of the circuit breaker in logs and events.
+func (b *circuitBreaker) target() string {
+	if b.prismCentral == "" {
+		return "Prism Central"
+	}
+	return "Prism Central " + b.prismCentral
+}
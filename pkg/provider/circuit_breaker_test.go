@@ -0,0 +1,169 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var _ = Describe("Test circuit breaker", func() { //nolint:typecheck
+	var (
+		ctx      context.Context
+		clock    *clocktesting.FakeClock
+		recorder *record.FakeRecorder
+		breaker  *circuitBreaker
+		flaky    *flakyPrism
+		prism    interfaces.Prism
+	)
+
+	getVM := func() error {
+		_, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+		return err
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mockEnvironment, err := mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+		Expect(err).ToNot(HaveOccurred())
+		mockPrism, err := mock.CreateMockClient(*mockEnvironment).Get()
+		Expect(err).ToNot(HaveOccurred())
+		flaky = &flakyPrism{Prism: mockPrism, err: interfaces.ErrUnavailable}
+
+		clock = clocktesting.NewFakeClock(time.Now())
+		recorder = record.NewFakeRecorder(10)
+		breaker = newCircuitBreaker(config.PrismCircuitBreaker{
+			FailureThreshold: 2,
+			OpenDuration:     metav1.Duration{Duration: time.Minute},
+		}, "", clock)
+		breaker.setEventRecorder(recorder, &v1.ObjectReference{Kind: "Pod", Namespace: "kube-system", Name: "ccm"})
+		prism = newRetryingPrism(flaky, newPrismClientPolicy(config.PrismClient{
+			Retry: config.PrismClientRetry{MaxAttempts: 1},
+		}, breaker))
+	})
+
+	It("should open after consecutive failures and fail fast while open", func() {
+		flaky.failures = 10
+		Expect(getVM()).To(MatchError(interfaces.ErrUnavailable))
+		Expect(breaker.state).To(Equal(circuitClosed))
+		Expect(getVM()).To(MatchError(interfaces.ErrUnavailable))
+		Expect(breaker.state).To(Equal(circuitOpen))
+		Expect(recorder.Events).To(Receive(ContainSubstring(prismCircuitOpenedReason)))
+
+		Expect(getVM()).To(MatchError(interfaces.ErrCircuitOpen))
+		Expect(flaky.calls).To(Equal(2))
+	})
+
+	It("should not count other errors as failures", func() {
+		flaky.failures = 1
+		Expect(getVM()).To(MatchError(interfaces.ErrUnavailable))
+		flaky.calls, flaky.failures, flaky.err = 0, 1, interfaces.ErrNotFound
+		Expect(getVM()).To(MatchError(interfaces.ErrNotFound))
+		flaky.calls, flaky.failures, flaky.err = 0, 1, interfaces.ErrUnavailable
+		Expect(getVM()).To(MatchError(interfaces.ErrUnavailable))
+		Expect(breaker.state).To(Equal(circuitClosed))
+	})
+
+	It("should close once a probe succeeds", func() {
+		flaky.failures = 2
+		Expect(getVM()).To(HaveOccurred())
+		Expect(getVM()).To(HaveOccurred())
+		Expect(breaker.state).To(Equal(circuitOpen))
+
+		clock.Step(time.Minute)
+		Expect(getVM()).To(Succeed())
+		Expect(breaker.state).To(Equal(circuitClosed))
+		Expect(recorder.Events).To(Receive(ContainSubstring(prismCircuitOpenedReason)))
+		Expect(recorder.Events).To(Receive(ContainSubstring(prismCircuitClosedReason)))
+	})
+
+	It("should reopen if a probe fails", func() {
+		flaky.failures = 3
+		Expect(getVM()).To(HaveOccurred())
+		Expect(getVM()).To(HaveOccurred())
+
+		clock.Step(time.Minute)
+		Expect(getVM()).To(MatchError(interfaces.ErrUnavailable))
+		Expect(breaker.state).To(Equal(circuitOpen))
+		Expect(getVM()).To(MatchError(interfaces.ErrCircuitOpen))
+		Expect(flaky.calls).To(Equal(3))
+	})
+
+	It("should only let a single probe through while half-open", func() {
+		breaker.state = circuitOpen
+		breaker.openedAt = clock.Now().Add(-time.Minute)
+		probe, err := breaker.allow()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(probe.probe).To(BeTrue())
+		Expect(breaker.state).To(Equal(circuitHalfOpen))
+		_, err = breaker.allow()
+		Expect(err).To(MatchError(interfaces.ErrCircuitOpen))
+		breaker.done(probe, nil)
+		Expect(breaker.state).To(Equal(circuitClosed))
+		_, err = breaker.allow()
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should ignore results of requests allowed before the circuit opened", func() {
+		slow, err := breaker.allow()
+		Expect(err).ToNot(HaveOccurred())
+		for range 2 {
+			ticket, err := breaker.allow()
+			Expect(err).ToNot(HaveOccurred())
+			breaker.done(ticket, interfaces.ErrUnavailable)
+		}
+		Expect(breaker.state).To(Equal(circuitOpen))
+
+		breaker.done(slow, nil)
+		Expect(breaker.state).To(Equal(circuitOpen))
+		_, err = breaker.allow()
+		Expect(err).To(MatchError(interfaces.ErrCircuitOpen))
+
+		clock.Step(time.Minute)
+		probe, err := breaker.allow()
+		Expect(err).ToNot(HaveOccurred())
+		breaker.done(slow, nil)
+		Expect(breaker.state).To(Equal(circuitHalfOpen))
+		breaker.done(probe, nil)
+		Expect(breaker.state).To(Equal(circuitClosed))
+	})
+
+	It("should name the Prism Central in events", func() {
+		breaker.prismCentral = "pc-2"
+		flaky.failures = 2
+		Expect(getVM()).To(HaveOccurred())
+		Expect(getVM()).To(HaveOccurred())
+		Expect(recorder.Events).To(Receive(ContainSubstring("Suspending requests to Prism Central pc-2")))
+	})
+
+	It("should not create a circuit breaker if disabled", func() {
+		Expect(newCircuitBreaker(config.PrismCircuitBreaker{Disabled: true}, "", clock)).To(BeNil())
+	})
+})
@@ -20,39 +20,61 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/nutanix-cloud-native/prism-go-client/converged"
 	convergedV4 "github.com/nutanix-cloud-native/prism-go-client/converged/v4"
 	"github.com/nutanix-cloud-native/prism-go-client/environment"
 	credentialtypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
 	kubernetesenv "github.com/nutanix-cloud-native/prism-go-client/environment/providers/kubernetes"
 	envtypes "github.com/nutanix-cloud-native/prism-go-client/environment/types"
+	prismclientv4 "github.com/nutanix-cloud-native/prism-go-client/v4"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/client-go/informers"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	klog "k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants" //nolint:typecheck
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingApi "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/api"
+	networkingClient "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/client"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	networkingPrismModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/prism/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 )
 
 const errEnvironmentNotReady = "environment not initialized or ready yet"
 
+// nutanixClientEnvironment is the client of a single Prism Central.
 type nutanixClientEnvironment struct {
+	// name of the Prism Central, empty if it is the only one
+	name              string
 	env               envtypes.Environment
 	config            config.Config
 	secretInformer    coreinformers.SecretInformer
 	sharedInformers   informers.SharedInformerFactory
 	configMapInformer coreinformers.ConfigMapInformer
 	clientCache       *convergedV4.ClientCache
+	// v4ClientCache caches the v4 SDK clients used for APIs the converged client does not cover
+	v4ClientCache *prismclientv4.ClientCache
+	// prismCache caches Prism lookups across clients, it is nil if caching is disabled
+	prismCache *prismCache
+	// prismClientPolicy rate limits and retries the requests of all clients of the Prism Central
+	prismClientPolicy *prismClientPolicy
+	// tracer traces the requests of all clients, nil disables tracing
+	tracer trace.Tracer
 }
 
-// Key returns the constant client name
+// Key returns the client name, suffixed with the name of the Prism Central if there are several
 // This implements the CachedClientParams interface of prism-go-client
 func (n *nutanixClientEnvironment) Key() string {
-	return constants.ClientName
+	if n.name == "" {
+		return constants.ClientName
+	}
+	return constants.ClientName + "/" + n.name
 }
 
 // ManagementEndpoint returns the management endpoint of the Nutanix cluster
@@ -89,7 +111,34 @@ func (n *nutanixClientEnvironment) Get() (interfaces.Prism, error) {
 	client := &nutanixClient{
 		convergedClient: convergedClient,
 	}
-	return client, nil
+
+	if n.v4ClientCache != nil {
+		client.newV4Client = func() (*prismclientv4.Client, error) {
+			return n.v4ClientCache.GetOrCreate(n)
+		}
+	}
+	prism := newInstrumentedPrism(client, n.tracer)
+	if n.prismClientPolicy != nil {
+		prism = newRetryingPrism(prism, n.prismClientPolicy)
+	}
+	// Cache hits are neither rate limited nor retried
+	if n.prismCache != nil {
+		prism = newCachedPrism(prism, n.prismCache)
+	}
+	return prism, nil
+}
+
+// GetForPrismCentral returns the client if it is of the named Prism Central.
+func (n *nutanixClientEnvironment) GetForPrismCentral(name string) (interfaces.Prism, error) {
+	if name != n.name {
+		return nil, fmt.Errorf("unknown Prism Central %q", name)
+	}
+	return n.Get()
+}
+
+// PrismCentrals returns the name of the Prism Central of the client.
+func (n *nutanixClientEnvironment) PrismCentrals() []string {
+	return []string{n.name}
 }
 
 func (n *nutanixClientEnvironment) setupEnvironment() error {
@@ -140,26 +189,286 @@ func (n *nutanixClientEnvironment) syncCache(informer cache.SharedInformer) {
 	}
 }
 
+// prismCentralClients is the client of several Prism Centrals, each of which has its own
+// environment, credentials and client cache key. Get returns the client of the first one.
+type prismCentralClients struct {
+	names   []string
+	clients map[string]*nutanixClientEnvironment
+	// circuitBreakers of the Prism Centrals, empty if the circuit breaker is disabled
+	circuitBreakers []*circuitBreaker
+}
+
+// newPrismCentralClients returns the clients of the Prism Centrals of the config. The client caches
+// and tracer are shared by all clients. The Prism cache is per Prism Central as UUIDs are only unique
+// within a Prism Central, and so are the client policy and circuit breaker as Prism Centrals fail
+// and throttle requests independently.
+func newPrismCentralClients(cfg config.Config, tracer trace.Tracer, clock clock.PassiveClock) *prismCentralClients {
+	clientCache := convergedV4.NewClientCache(prismclientv4.WithSessionAuth(true))
+	v4ClientCache := prismclientv4.NewClientCache(prismclientv4.WithSessionAuth(true))
+	endpoints := cfg.PrismCentralEndpoints()
+	clients := &prismCentralClients{
+		names:   make([]string, 0, len(endpoints)),
+		clients: make(map[string]*nutanixClientEnvironment, len(endpoints)),
+	}
+	for _, endpoint := range endpoints {
+		endpointConfig := cfg
+		endpointConfig.PrismCentral = endpoint.NutanixPrismEndpoint
+		endpointConfig.PrismCentrals = nil
+		breaker := newCircuitBreaker(cfg.PrismClient.CircuitBreaker, endpoint.Name, clock)
+		if breaker != nil {
+			clients.circuitBreakers = append(clients.circuitBreakers, breaker)
+		}
+		clients.names = append(clients.names, endpoint.Name)
+		clients.clients[endpoint.Name] = &nutanixClientEnvironment{
+			name:              endpoint.Name,
+			config:            endpointConfig,
+			clientCache:       clientCache,
+			v4ClientCache:     v4ClientCache,
+			prismCache:        newPrismCache(cfg.PrismCache),
+			prismClientPolicy: newPrismClientPolicy(cfg.PrismClient, breaker),
+			tracer:            tracer,
+		}
+	}
+	return clients
+}
+
+func (c *prismCentralClients) Get() (interfaces.Prism, error) {
+	return c.GetForPrismCentral(c.names[0])
+}
+
+func (c *prismCentralClients) GetForPrismCentral(name string) (interfaces.Prism, error) {
+	client, ok := c.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown Prism Central %q", name)
+	}
+	return client.Get()
+}
+
+func (c *prismCentralClients) PrismCentrals() []string {
+	return c.names
+}
+
+func (c *prismCentralClients) SetInformers(sharedInformers informers.SharedInformerFactory) {
+	for _, name := range c.names {
+		c.clients[name].SetInformers(sharedInformers)
+	}
+}
+
 type nutanixClient struct {
 	convergedClient *convergedV4.Client
+	// newV4Client returns the v4 SDK client, which is only needed by the networking APIs, so it is
+	// created on their first use
+	newV4Client func() (*prismclientv4.Client, error)
 }
 
 func (client *nutanixClient) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
-	return client.convergedClient.VMs.Get(ctx, vmUUID)
+	return prismResult(client.convergedClient.VMs.Get(ctx, vmUUID))
+}
+
+func (client *nutanixClient) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	opts := make([]converged.ODataOption, 0)
+	if filter != "" {
+		opts = append(opts, converged.WithFilter(filter))
+	}
+	return prismResult(client.convergedClient.VMs.List(ctx, opts...))
 }
 
 func (client *nutanixClient) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
-	return client.convergedClient.Clusters.Get(ctx, clusterUUID)
+	return prismResult(client.convergedClient.Clusters.Get(ctx, clusterUUID))
 }
 
 func (client *nutanixClient) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
-	return client.convergedClient.Clusters.List(ctx)
+	return prismResult(client.convergedClient.Clusters.List(ctx))
 }
 
 func (client *nutanixClient) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
-	return client.convergedClient.Categories.Get(ctx, categoryUUID)
+	return prismResult(client.convergedClient.Categories.Get(ctx, categoryUUID))
+}
+
+func (client *nutanixClient) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	opts := []converged.ODataOption{converged.WithExpand("detailedAssociations")}
+	if filter != "" {
+		opts = append(opts, converged.WithFilter(filter))
+	}
+	return prismResult(client.convergedClient.Categories.List(ctx, opts...))
 }
 
 func (client *nutanixClient) GetClusterHost(ctx context.Context, clusterUuid string, hostUUID string) (*clusterModels.Host, error) {
-	return client.convergedClient.Clusters.GetClusterHost(ctx, clusterUuid, hostUUID)
+	return prismResult(client.convergedClient.Clusters.GetClusterHost(ctx, clusterUuid, hostUUID))
+}
+
+func (client *nutanixClient) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	return prismResult(client.convergedClient.Subnets.Get(ctx, subnetUUID))
+}
+
+func (client *nutanixClient) ListLoadBalancerSessions(ctx context.Context, filter string) ([]networkingModels.LoadBalancerSession, error) {
+	api, err := client.loadBalancerSessionsApi()
+	if err != nil {
+		return nil, err
+	}
+	opts := make([]converged.ODataOption, 0)
+	if filter != "" {
+		opts = append(opts, converged.WithFilter(filter))
+	}
+	return prismResult(convergedV4.GenericListEntities[*networkingModels.ListLoadBalancerSessionsApiResponse, networkingModels.LoadBalancerSession](
+		func(reqParams *convergedV4.V4ODataParams) (*networkingModels.ListLoadBalancerSessionsApiResponse, error) {
+			return api.ListLoadBalancerSessions(reqParams.Page, reqParams.Limit, reqParams.Filter, reqParams.OrderBy, reqParams.Select)
+		},
+		opts,
+		"load balancer sessions",
+	))
+}
+
+func (client *nutanixClient) CreateLoadBalancerSession(ctx context.Context, session *networkingModels.LoadBalancerSession) error {
+	api, err := client.loadBalancerSessionsApi()
+	if err != nil {
+		return err
+	}
+	taskRef, err := convergedV4.CallAPI[*networkingModels.TaskReferenceApiResponse, networkingPrismModels.TaskReference](
+		api.CreateLoadBalancerSession(session),
+	)
+	if err != nil {
+		return toPrismError(fmt.Errorf("failed to create load balancer session: %w", err))
+	}
+	return client.waitForTask(ctx, taskRef)
+}
+
+func (client *nutanixClient) UpdateLoadBalancerSession(ctx context.Context, sessionUUID string, session *networkingModels.LoadBalancerSession) error {
+	api, err := client.loadBalancerSessionsApi()
+	if err != nil {
+		return err
+	}
+	current, args, err := convergedV4.GetEntityAndEtag(api.GetLoadBalancerSessionById(&sessionUUID, nil))
+	if err != nil {
+		return toPrismError(fmt.Errorf("failed to get load balancer session %s for update: %w", sessionUUID, err))
+	}
+	session = convergedV4.CopyEtag(current, session).(*networkingModels.LoadBalancerSession)
+
+	taskRef, err := convergedV4.CallAPI[*networkingModels.TaskReferenceApiResponse, networkingPrismModels.TaskReference](
+		api.UpdateLoadBalancerSessionById(&sessionUUID, session, args),
+	)
+	if err != nil {
+		return toPrismError(fmt.Errorf("failed to update load balancer session %s: %w", sessionUUID, err))
+	}
+	return client.waitForTask(ctx, taskRef)
+}
+
+func (client *nutanixClient) DeleteLoadBalancerSession(ctx context.Context, sessionUUID string) error {
+	api, err := client.loadBalancerSessionsApi()
+	if err != nil {
+		return err
+	}
+	taskRef, err := convergedV4.CallAPI[*networkingModels.TaskReferenceApiResponse, networkingPrismModels.TaskReference](
+		api.DeleteLoadBalancerSessionById(&sessionUUID),
+	)
+	if err != nil {
+		return toPrismError(fmt.Errorf("failed to delete load balancer session %s: %w", sessionUUID, err))
+	}
+	return client.waitForTask(ctx, taskRef)
+}
+
+func (client *nutanixClient) ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error) {
+	apiClient, err := client.networkingApiClient()
+	if err != nil {
+		return nil, err
+	}
+	api := networkingApi.NewRouteTablesApi(apiClient)
+	opts := make([]converged.ODataOption, 0)
+	if filter != "" {
+		opts = append(opts, converged.WithFilter(filter))
+	}
+	return prismResult(convergedV4.GenericListEntities[*networkingModels.ListRouteTablesApiResponse, networkingModels.RouteTable](
+		func(reqParams *convergedV4.V4ODataParams) (*networkingModels.ListRouteTablesApiResponse, error) {
+			return api.ListRouteTables(reqParams.Page, reqParams.Limit, reqParams.Filter, reqParams.OrderBy)
+		},
+		opts,
+		"route tables",
+	))
+}
+
+func (client *nutanixClient) ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error) {
+	apiClient, err := client.networkingApiClient()
+	if err != nil {
+		return nil, err
+	}
+	api := networkingApi.NewRoutesApi(apiClient)
+	opts := make([]converged.ODataOption, 0)
+	if filter != "" {
+		opts = append(opts, converged.WithFilter(filter))
+	}
+	return prismResult(convergedV4.GenericListEntities[*networkingModels.ListRoutesApiResponse, networkingModels.Route](
+		func(reqParams *convergedV4.V4ODataParams) (*networkingModels.ListRoutesApiResponse, error) {
+			return api.ListRoutesByRouteTableId(&routeTableUUID, reqParams.Page, reqParams.Limit, reqParams.Filter, reqParams.OrderBy)
+		},
+		opts,
+		"routes",
+	))
+}
+
+func (client *nutanixClient) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error {
+	apiClient, err := client.networkingApiClient()
+	if err != nil {
+		return err
+	}
+	taskRef, err := convergedV4.CallAPI[*networkingModels.TaskReferenceApiResponse, networkingPrismModels.TaskReference](
+		networkingApi.NewRoutesApi(apiClient).CreateRouteForRouteTable(&routeTableUUID, route),
+	)
+	if err != nil {
+		return toPrismError(fmt.Errorf("failed to create route in route table %s: %w", routeTableUUID, err))
+	}
+	return client.waitForTask(ctx, taskRef)
+}
+
+func (client *nutanixClient) DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error {
+	apiClient, err := client.networkingApiClient()
+	if err != nil {
+		return err
+	}
+	taskRef, err := convergedV4.CallAPI[*networkingModels.TaskReferenceApiResponse, networkingPrismModels.TaskReference](
+		networkingApi.NewRoutesApi(apiClient).DeleteRouteForRouteTableById(&routeUUID, &routeTableUUID),
+	)
+	if err != nil {
+		return toPrismError(fmt.Errorf("failed to delete route %s from route table %s: %w", routeUUID, routeTableUUID, err))
+	}
+	return client.waitForTask(ctx, taskRef)
+}
+
+func (client *nutanixClient) loadBalancerSessionsApi() (*networkingApi.LoadBalancerSessionsApi, error) {
+	apiClient, err := client.networkingApiClient()
+	if err != nil {
+		return nil, err
+	}
+	return networkingApi.NewLoadBalancerSessionsApi(apiClient), nil
+}
+
+// v4Client returns the v4 SDK client of the Prism Central.
+func (client *nutanixClient) v4Client() (*prismclientv4.Client, error) {
+	if client.newV4Client == nil {
+		return nil, fmt.Errorf("v4 client not initialized")
+	}
+	return client.newV4Client()
+}
+
+// networkingApiClient returns the API client shared by all networking APIs
+func (client *nutanixClient) networkingApiClient() (*networkingClient.ApiClient, error) {
+	v4Client, err := client.v4Client()
+	if err != nil {
+		return nil, err
+	}
+	if v4Client.SubnetsApiInstance == nil {
+		return nil, fmt.Errorf("networking client not initialized")
+	}
+	return v4Client.SubnetsApiInstance.ApiClient, nil
+}
+
+func (client *nutanixClient) waitForTask(ctx context.Context, taskRef networkingPrismModels.TaskReference) error {
+	if taskRef.ExtId == nil {
+		return fmt.Errorf("task reference ExtId is nil")
+	}
+	v4Client, err := client.v4Client()
+	if err != nil {
+		return err
+	}
+	_, err = convergedV4.NewOperation(*taskRef.ExtId, v4Client, converged.NoEntityGetter).Wait(ctx)
+	return toPrismError(err)
 }
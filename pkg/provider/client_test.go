@@ -29,16 +29,17 @@ import (
 	. "github.com/onsi/gomega"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
-	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	configpkg "github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
 
 var _ = Describe("Test Client", func() { // nolint:typecheck
 	var (
 		kClient         *fake.Clientset
-		config          config.Config
+		config          configpkg.Config
 		nClient         nutanixClientEnvironment
 		informerFactory informers.SharedInformerFactory
 	)
@@ -63,6 +64,41 @@ var _ = Describe("Test Client", func() { // nolint:typecheck
 		It("should return the client name", func() { //nolint:typecheck
 			Expect(nClient.Key()).To(Equal(constants.ClientName)) //nolint:typecheck
 		})
+
+		It("should suffix the client name with the Prism Central name", func() { //nolint:typecheck
+			nClient.name = "pc-2"
+			Expect(nClient.Key()).To(Equal(constants.ClientName + "/pc-2")) //nolint:typecheck
+		})
+
+		It("should use a key per Prism Central", func() { //nolint:typecheck
+			config.PrismCentrals = []configpkg.PrismCentralEndpoint{
+				{Name: "pc-1", NutanixPrismEndpoint: config.PrismCentral},
+				{Name: "pc-2", NutanixPrismEndpoint: config.PrismCentral},
+			}
+			config.PrismCentral = credentials.NutanixPrismEndpoint{}
+			clients := newPrismCentralClients(config, nil, clock.RealClock{})
+			Expect(clients.PrismCentrals()).To(Equal([]string{"pc-1", "pc-2"}))
+			Expect(clients.clients["pc-1"].Key()).ToNot(Equal(clients.clients["pc-2"].Key()))
+			Expect(clients.clients["pc-2"].config.PrismCentral).To(Equal(config.PrismCentrals[1].NutanixPrismEndpoint))
+			_, err := clients.GetForPrismCentral("pc-3")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should use a client policy and circuit breaker per Prism Central", func() { //nolint:typecheck
+			config.PrismCentrals = []configpkg.PrismCentralEndpoint{
+				{Name: "pc-1", NutanixPrismEndpoint: config.PrismCentral},
+				{Name: "pc-2", NutanixPrismEndpoint: config.PrismCentral},
+			}
+			config.PrismCentral = credentials.NutanixPrismEndpoint{}
+			clients := newPrismCentralClients(config, nil, clock.RealClock{})
+			policy1 := clients.clients["pc-1"].prismClientPolicy
+			policy2 := clients.clients["pc-2"].prismClientPolicy
+			Expect(policy1).ToNot(BeIdenticalTo(policy2))
+			Expect(policy1.limiter).ToNot(BeIdenticalTo(policy2.limiter))
+			Expect(policy1.breaker.prismCentral).To(Equal("pc-1"))
+			Expect(policy2.breaker.prismCentral).To(Equal("pc-2"))
+			Expect(clients.circuitBreakers).To(ConsistOf(policy1.breaker, policy2.breaker))
+		})
 	})
 
 	Context("Test ManagementEndpoint", func() { //nolint:typecheck
@@ -17,23 +17,59 @@ limitations under the License.
 package config
 
 import (
-	"encoding/json"
 	"fmt"
+	"os"
 
 	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
-	klog "k8s.io/klog/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	sigsjson "sigs.k8s.io/json"
+	sigsyaml "sigs.k8s.io/yaml"
 )
 
 // Config of Nutanix provider
 type Config struct {
+	metav1.TypeMeta      `json:",inline"`
 	PrismCentral         credentialTypes.NutanixPrismEndpoint `json:"prismCentral"`
+	PrismCentrals        []PrismCentralEndpoint               `json:"prismCentrals,omitempty"`
 	TopologyDiscovery    TopologyDiscovery                    `json:"topologyDiscovery"`
 	EnableCustomLabeling bool                                 `json:"enableCustomLabeling"`
+	ProviderIDFormat     ProviderIDFormat                     `json:"providerIDFormat,omitempty"`
+	VMLookup             *VMLookup                            `json:"vmLookup,omitempty"`
+	VMPowerStates        map[string]NodeState                 `json:"vmPowerStates,omitempty"`
 	IgnoredNodeIPs       []string                             `json:"ignoredNodeIPs,omitempty"`
+	NodeIPFamilies       []IPFamily                           `json:"nodeIPFamilies,omitempty"`
+	NodeAddressRules     []NodeAddressRule                    `json:"nodeAddressRules,omitempty"`
+	NodeDNS              *NodeDNS                             `json:"nodeDNS,omitempty"`
+	InstanceTypes        *InstanceTypes                       `json:"instanceTypes,omitempty"`
+	CategoryLabels       *CategoryLabels                      `json:"categoryLabels,omitempty"`
+	PrismCache           PrismCache                           `json:"prismCache,omitempty"`
+	PrismClient          PrismClient                          `json:"prismClient,omitempty"`
+	Tracing              *Tracing                             `json:"tracing,omitempty"`
+	LoadBalancer         *LoadBalancer                        `json:"loadBalancer,omitempty"`
+	Routes               *Routes                              `json:"routes,omitempty"`
+}
+
+// PrismCentralEndpoint is an entry of PrismCentrals, which replaces PrismCentral for clusters whose
+// VMs are managed by more than one Prism Central. The VM of a node is looked up in the Prism
+// Centrals in order, and the first one is used for load balancers and routes.
+type PrismCentralEndpoint struct {
+	// Name identifies the Prism Central in logs and must be unique
+	Name                                 string `json:"name"`
+	credentialTypes.NutanixPrismEndpoint `json:",inline"`
+}
+
+// PrismCentralEndpoints returns the configured Prism Centrals. A single PrismCentral has an empty name.
+func (c *Config) PrismCentralEndpoints() []PrismCentralEndpoint {
+	if len(c.PrismCentrals) > 0 {
+		return c.PrismCentrals
+	}
+	return []PrismCentralEndpoint{{NutanixPrismEndpoint: c.PrismCentral}}
 }
 
 type TopologyDiscovery struct {
-	// Default type will be set to Prism via the newConfig function
+	// Defaults to Prism
 	Type               TopologyDiscoveryType `json:"type"`
 	TopologyCategories *TopologyCategories   `json:"topologyCategories"`
 }
@@ -45,6 +81,216 @@ const (
 	CategoriesTopologyDiscoveryType = TopologyDiscoveryType("Categories")
 )
 
+// ProviderIDFormat is the format of the provider IDs of new nodes. Provider IDs of both formats are
+// accepted regardless of the format, so existing nodes keep their provider ID. Defaults to UUID.
+type ProviderIDFormat string
+
+const (
+	// UUIDProviderIDFormat is nutanix://<vm-uuid>
+	UUIDProviderIDFormat = ProviderIDFormat("UUID")
+	// QualifiedProviderIDFormat is nutanix://<prism-central-uuid>/<cluster-uuid>/<vm-uuid>, which
+	// identifies the Prism Central and Prism Element cluster of the VM
+	QualifiedProviderIDFormat = ProviderIDFormat("Qualified")
+)
+
+// VMLookup configures how the VM of a node is found if the node has no system UUID or no VM has
+// its system UUID, e.g. because the VM was cloned from a template or runs nested virtualization.
+// Only the system UUID is used when this section is omitted.
+type VMLookup struct {
+	// Fallbacks are the strategies tried in order. The lookup fails if more than one VM matches.
+	Fallbacks []VMLookupStrategy `json:"fallbacks"`
+	// Annotation of the node holding the UUID of its VM, used by the Annotation strategy.
+	// Defaults to nutanix.com/vm-uuid.
+	Annotation string `json:"annotation,omitempty"`
+	// InternalIPFilter is an OData filter narrowing down the VMs listed by the InternalIP strategy,
+	// e.g. to a cluster or name prefix. Without it, the strategy lists every VM of every Prism
+	// Central, which is costly on large Prism Centrals. The lists are cached for the VM TTL of the
+	// Prism cache, so that the nodes initialized at the same time share them.
+	InternalIPFilter string `json:"internalIPFilter,omitempty"`
+}
+
+type VMLookupStrategy string
+
+const (
+	// NameVMLookupStrategy finds the VM whose name is the node name
+	NameVMLookupStrategy = VMLookupStrategy("Name")
+	// InternalIPVMLookupStrategy finds the VM with a NIC IP which is an InternalIP of the node
+	InternalIPVMLookupStrategy = VMLookupStrategy("InternalIP")
+	// AnnotationVMLookupStrategy finds the VM whose UUID is in the annotation of the node
+	AnnotationVMLookupStrategy = VMLookupStrategy("Annotation")
+)
+
+// NodeState is the state of a node reported for the power state of its VM in VMPowerStates, which
+// maps the AHV power states ON, OFF, PAUSED and UNDETERMINED. Power states which are not mapped keep
+// their default: ON is Running, OFF and PAUSED are Shutdown, and UNDETERMINED is Unknown.
+type NodeState string
+
+const (
+	// RunningNodeState reports the node as not shut down
+	RunningNodeState = NodeState("Running")
+	// ShutdownNodeState reports the node as shut down
+	ShutdownNodeState = NodeState("Shutdown")
+	// UnknownNodeState fails the shutdown check of the node, so that it is retried
+	UnknownNodeState = NodeState("Unknown")
+)
+
+// IPFamily is an entry of NodeIPFamilies, which selects the IP families of node addresses in
+// order of preference, e.g. [IPv6, IPv4] for IPv6-first dual-stack nodes. Defaults to [IPv4].
+type IPFamily string
+
+const (
+	IPv4IPFamily = IPFamily("IPv4")
+	IPv6IPFamily = IPFamily("IPv6")
+)
+
+// NodeAddressRule assigns a type to the node addresses matching any of its CIDRs, subnet names
+// or subnet UUIDs. Rules are evaluated in order and the first matching rule wins. Addresses not
+// matching any rule are reported as InternalIP.
+type NodeAddressRule struct {
+	Type NodeAddressType `json:"type"`
+	// CIDRs accepts single IPs, CIDR prefixes and IP ranges, using the same
+	// format as IgnoredNodeIPs
+	CIDRs []string `json:"cidrs,omitempty"`
+	// SubnetNames match the name of the Prism subnet the NIC of an address is attached to
+	SubnetNames []string `json:"subnetNames,omitempty"`
+	// SubnetUUIDs match the UUID of the Prism subnet the NIC of an address is attached to
+	SubnetUUIDs []string `json:"subnetUUIDs,omitempty"`
+}
+
+type NodeAddressType string
+
+const (
+	InternalIPNodeAddressType = NodeAddressType("InternalIP")
+	ExternalIPNodeAddressType = NodeAddressType("ExternalIP")
+)
+
+// NodeDNS configures the InternalDNS and ExternalDNS node addresses. The templates use Go
+// text/template syntax and can reference .VMName, .VMUUID, .ClusterName, .ClusterUUID, .HostName
+// and .HostUUID, e.g. "{{.VMName}}.{{.ClusterName}}.corp.example". No address of a type is
+// reported if its template is not set or renders an empty string.
+type NodeDNS struct {
+	InternalDNSTemplate string `json:"internalDNSTemplate,omitempty"`
+	ExternalDNSTemplate string `json:"externalDNSTemplate,omitempty"`
+}
+
+// InstanceTypes enables deriving the instance type of nodes from the shape of their VM.
+// The instance type of all nodes is "ahv-vm" when this section is omitted.
+type InstanceTypes struct {
+	// Classes name VM shapes. Classes are evaluated in order and the first class matching
+	// the VM is used. VMs not matching any class are named after their shape, e.g. ahv-8c-32g.
+	Classes []InstanceClass `json:"classes,omitempty"`
+}
+
+// InstanceClass matches VMs of the given shape. Unset fields match any value.
+type InstanceClass struct {
+	Name      string `json:"name"`
+	VCPUs     int    `json:"vcpus,omitempty"`
+	MemoryGiB int    `json:"memoryGiB,omitempty"`
+	GPUs      *int   `json:"gpus,omitempty"`
+}
+
+// CategoryLabels propagates the Prism categories attached to the VM of a node, the AHV host running
+// it or its cluster to node labels. Categories of the VM take precedence over categories of the
+// host, which take precedence over categories of the cluster.
+// Category propagation is disabled when this section is omitted.
+type CategoryLabels struct {
+	// Domain prefixes the label keys, e.g. category.nutanix.com/<category key>.
+	// Defaults to category.nutanix.com.
+	Domain string `json:"domain,omitempty"`
+	// KeyPrefixes selects the categories whose key starts with any of the prefixes
+	KeyPrefixes []string `json:"keyPrefixes,omitempty"`
+	// Keys selects categories by key
+	Keys []string `json:"keys,omitempty"`
+}
+
+// PrismCache configures the read-through cache of Prism VM, VM list, cluster, host, category and
+// subnet lookups. Unset values use the defaults of the cloud provider.
+type PrismCache struct {
+	// Disabled sends every lookup to Prism
+	Disabled bool `json:"disabled,omitempty"`
+	// MaxEntries bounds the number of cached entities
+	MaxEntries int `json:"maxEntries,omitempty"`
+	// VMTTL bounds how long VM changes take to be observed by node initialization and labeling, and
+	// also applies to the VM lists of the fallback VM lookups. Node existence and shutdown checks
+	// always look up the VM in Prism.
+	VMTTL      metav1.Duration `json:"vmTTL,omitempty"`
+	ClusterTTL metav1.Duration `json:"clusterTTL,omitempty"`
+	HostTTL    metav1.Duration `json:"hostTTL,omitempty"`
+	// CategoryTTL also applies to the category lists used to find the categories of hosts
+	CategoryTTL metav1.Duration `json:"categoryTTL,omitempty"`
+	SubnetTTL   metav1.Duration `json:"subnetTTL,omitempty"`
+	// NotFoundTTL is how long lookups of entities which do not exist are cached
+	NotFoundTTL metav1.Duration `json:"notFoundTTL,omitempty"`
+}
+
+// PrismClient configures the rate limiting and retries of requests to Prism Central. Unset values use
+// the defaults of the cloud provider.
+type PrismClient struct {
+	// QPS is the sustained rate of requests to Prism Central
+	QPS float32 `json:"qps,omitempty"`
+	// Burst is the number of requests allowed above the sustained rate
+	Burst          int                 `json:"burst,omitempty"`
+	Retry          PrismClientRetry    `json:"retry,omitempty"`
+	CircuitBreaker PrismCircuitBreaker `json:"circuitBreaker,omitempty"`
+}
+
+// PrismClientRetry configures the exponential backoff of failed requests to Prism Central. Requests
+// which create, update or delete entities are not retried.
+type PrismClientRetry struct {
+	// MaxAttempts includes the first attempt, 1 disables retries
+	MaxAttempts    int             `json:"maxAttempts,omitempty"`
+	InitialBackoff metav1.Duration `json:"initialBackoff,omitempty"`
+	MaxBackoff     metav1.Duration `json:"maxBackoff,omitempty"`
+	// Jitter randomly extends each backoff by up to this fraction of it
+	Jitter *float64 `json:"jitter,omitempty"`
+	// RetryOn lists the classes of errors which are retried
+	RetryOn []PrismErrorClass `json:"retryOn,omitempty"`
+}
+
+// PrismCircuitBreaker configures when requests to Prism Central are suspended. The circuit opens
+// after consecutive requests failed because Prism Central was unavailable, and requests fail
+// immediately until the open duration passed. A single probe request then decides whether the
+// circuit closes again.
+type PrismCircuitBreaker struct {
+	Disabled         bool            `json:"disabled,omitempty"`
+	FailureThreshold int             `json:"failureThreshold,omitempty"`
+	OpenDuration     metav1.Duration `json:"openDuration,omitempty"`
+}
+
+type PrismErrorClass string
+
+const (
+	ThrottledPrismErrorClass    PrismErrorClass = "Throttled"
+	UnavailablePrismErrorClass  PrismErrorClass = "Unavailable"
+	UnauthorizedPrismErrorClass PrismErrorClass = "Unauthorized"
+)
+
+// Tracing exports OpenTelemetry traces of the InstancesV2 operations and the Prism Central requests
+// they send. Tracing is disabled when this section is omitted, unless it is enabled by the
+// --tracing-* flags, which take precedence over the values set here.
+type Tracing struct {
+	Exporter TracingExporter `json:"exporter"`
+	// Endpoint is the host:port of the OTLP gRPC collector. Defaults to localhost:4317.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Insecure disables TLS towards the OTLP collector
+	Insecure bool `json:"insecure,omitempty"`
+	// FilePath is the file the File exporter appends spans to
+	FilePath string `json:"filePath,omitempty"`
+	// SamplingRatio is the fraction of traces which are recorded. Defaults to 1.
+	SamplingRatio *float64 `json:"samplingRatio,omitempty"`
+}
+
+type TracingExporter string
+
+const (
+	// OTLPTracingExporter sends spans to an OpenTelemetry collector over gRPC
+	OTLPTracingExporter = TracingExporter("OTLP")
+	// StdoutTracingExporter writes spans as JSON to the standard output
+	StdoutTracingExporter = TracingExporter("Stdout")
+	// FileTracingExporter writes spans as JSON to a local file for offline debugging
+	FileTracingExporter = TracingExporter("File")
+)
+
 type TopologyInfo struct {
 	Zone   string `json:"zone"`
 	Region string `json:"region"`
@@ -55,23 +301,83 @@ type TopologyCategories struct {
 	RegionCategory string `json:"regionCategory"`
 }
 
+// LoadBalancer configures the Service type LoadBalancer implementation.
+// Load balancer support is disabled when this section is omitted.
+type LoadBalancer struct {
+	// Defaults to IPPool
+	Type LoadBalancerType `json:"type,omitempty"`
+	// IPPools are the pools virtual IPs are allocated from. Pools are tried in
+	// order unless a Service selects one explicitly.
+	// Optional for the FlowVPC type, in which case Prism assigns the virtual IPs.
+	IPPools []IPPool `json:"ipPools,omitempty"`
+	// FlowVPC must be set when using load balancer type FlowVPC
+	FlowVPC *FlowVPCLoadBalancer `json:"flowVPC,omitempty"`
+}
+
+type LoadBalancerType string
+
+const (
+	// IPPoolLoadBalancerType only allocates virtual IPs, traffic is expected to be
+	// attracted by an in-cluster component such as kube-vip or MetalLB
+	IPPoolLoadBalancerType = LoadBalancerType("IPPool")
+	// FlowVPCLoadBalancerType creates load balancer sessions in a Flow Virtual Networking VPC
+	FlowVPCLoadBalancerType = LoadBalancerType("FlowVPC")
+)
+
+// FlowVPCLoadBalancer configures load balancer sessions in a VPC of the first Prism Central. Nodes
+// whose VM is managed by another Prism Central are not load balancer targets.
+type FlowVPCLoadBalancer struct {
+	// VPCUUID is the VPC the nodes are attached to
+	VPCUUID string `json:"vpcUUID"`
+	// VIPSubnetUUID is the subnet the virtual IPs are assigned in
+	VIPSubnetUUID string `json:"vipSubnetUUID"`
+	// BackendSubnetUUID selects the node NIC used as load balancer target.
+	// The first NIC of the node VM is used if not set.
+	BackendSubnetUUID string `json:"backendSubnetUUID,omitempty"`
+}
+
+// Routes configures the Routes implementation, which programs the pod CIDR of
+// every node into the route table of a Flow Virtual Networking VPC of the first Prism Central. The
+// next hop of a route is the InternalIP of its node, regardless of the Prism Central of its VM.
+// Route support is disabled when this section is omitted.
+type Routes struct {
+	// VPCUUID is the VPC the nodes are attached to
+	VPCUUID string `json:"vpcUUID"`
+	// RouteTableUUID is the route table the routes are created in.
+	// The route table of the VPC is looked up if not set.
+	RouteTableUUID string `json:"routeTableUUID,omitempty"`
+}
+
+type IPPool struct {
+	Name string `json:"name"`
+	// Addresses accepts single IPs, CIDR prefixes and IP ranges, using the same
+	// format as IgnoredNodeIPs. The network and broadcast addresses of IPv4
+	// prefixes are not allocated.
+	Addresses []string `json:"addresses"`
+}
+
+// NewConfigFromBytes decodes the config from JSON or YAML, rejecting unknown and duplicate fields,
+// and converts it to the current version. The environment variable overrides are applied before the defaults of unset fields, and the
+// resulting config is validated. All problems are reported at once.
 func NewConfigFromBytes(bytes []byte) (Config, error) {
 	nutanixConfig := Config{}
-	if err := json.Unmarshal(bytes, &nutanixConfig); err != nil {
+	if !utilyaml.IsJSONBuffer(bytes) {
+		var err error
+		if bytes, err = sigsyaml.YAMLToJSONStrict(bytes); err != nil {
+			return nutanixConfig, fmt.Errorf("failed to parse config as YAML: %w", err)
+		}
+	}
+	strictErrs, err := sigsjson.UnmarshalStrict(bytes, &nutanixConfig, sigsjson.DisallowDuplicateFields, sigsjson.DisallowUnknownFields)
+	if err != nil {
 		return nutanixConfig, err
 	}
-	switch nutanixConfig.TopologyDiscovery.Type {
-	case PrismTopologyDiscoveryType:
-		return nutanixConfig, nil
-	case "":
-		klog.Warningf("topology discovery type was not set. Defaulting to %s", PrismTopologyDiscoveryType)
-		nutanixConfig.TopologyDiscovery.Type = PrismTopologyDiscoveryType
-		return nutanixConfig, nil
-	case CategoriesTopologyDiscoveryType:
-		if nutanixConfig.TopologyDiscovery.TopologyCategories == nil {
-			return nutanixConfig, fmt.Errorf("topologyCategories must be set when using topology discovery type: %s", CategoriesTopologyDiscoveryType)
-		}
-		return nutanixConfig, nil
+	if err := nutanixConfig.convert(); err != nil {
+		return nutanixConfig, err
+	}
+	errs := append(strictErrs, nutanixConfig.applyEnvOverrides(os.LookupEnv)...)
+	SetDefaults(&nutanixConfig)
+	if err := nutanixConfig.Validate(); err != nil {
+		errs = append(errs, err)
 	}
-	return nutanixConfig, fmt.Errorf("unsupported topology discovery type: %s", nutanixConfig.TopologyDiscovery.Type)
+	return nutanixConfig, utilerrors.Flatten(utilerrors.NewAggregate(errs))
 }
@@ -0,0 +1,177 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewConfigFromBytes(t *testing.T) {
+	want := validConfig()
+	want.APIVersion = APIVersion
+	want.Kind = Kind
+	want.TopologyDiscovery = TopologyDiscovery{
+		Type:               CategoriesTopologyDiscoveryType,
+		TopologyCategories: &TopologyCategories{ZoneCategory: "zone"},
+	}
+	want.ProviderIDFormat = UUIDProviderIDFormat
+	want.IgnoredNodeIPs = []string{"10.0.0.1"}
+	want.PrismCache.VMTTL = metav1.Duration{Duration: time.Minute}
+
+	defaulted := validConfig()
+	defaulted.APIVersion = APIVersion
+	defaulted.Kind = Kind
+	defaulted.TopologyDiscovery.Type = PrismTopologyDiscoveryType
+	defaulted.ProviderIDFormat = UUIDProviderIDFormat
+
+	tests := []struct {
+		name  string
+		input string
+		want  Config
+		// wantErrs are the expected problems, none if the config is valid
+		wantErrs []string
+	}{
+		{
+			name: "YAML",
+			input: `
+prismCentral:
+  address: pc.example.com
+  port: 9440
+  credentialRef:
+    kind: Secret
+    name: nutanix-creds
+topologyDiscovery:
+  type: Categories
+  topologyCategories:
+    zoneCategory: zone
+ignoredNodeIPs:
+  - 10.0.0.1
+prismCache:
+  vmTTL: 1m
+`,
+			want: want,
+		},
+		{
+			name: "JSON",
+			input: `{
+				"prismCentral": {"address": "pc.example.com", "port": 9440, "credentialRef": {"kind": "Secret", "name": "nutanix-creds"}},
+				"topologyDiscovery": {"type": "Categories", "topologyCategories": {"zoneCategory": "zone"}},
+				"ignoredNodeIPs": ["10.0.0.1"],
+				"prismCache": {"vmTTL": "1m"}
+			}`,
+			want: want,
+		},
+		{
+			name: "versioned JSON with unset fields",
+			input: `{
+				"apiVersion": "config.cloudprovider.nutanix.com/v1alpha1",
+				"kind": "NutanixCloudConfig",
+				"prismCentral": {"address": "pc.example.com", "credentialRef": {"name": "nutanix-creds"}}
+			}`,
+			want: defaulted,
+		},
+		{
+			name: "unversioned JSON with lower case credential kind",
+			input: `{
+				"prismCentral": {"address": "pc.example.com", "credentialRef": {"kind": "secret", "name": "nutanix-creds"}}
+			}`,
+			want: defaulted,
+		},
+		{
+			name: "versioned JSON with lower case credential kind",
+			input: `{
+				"apiVersion": "config.cloudprovider.nutanix.com/v1alpha1",
+				"kind": "NutanixCloudConfig",
+				"prismCentral": {"address": "pc.example.com", "credentialRef": {"kind": "secret", "name": "nutanix-creds"}}
+			}`,
+			wantErrs: []string{`prismCentral.credentialRef.kind: Unsupported value: "secret"`},
+		},
+		{
+			name:     "unsupported apiVersion",
+			input:    `{"apiVersion": "config.cloudprovider.nutanix.com/v1", "kind": "NutanixCloudConfig"}`,
+			wantErrs: []string{`unsupported config apiVersion "config.cloudprovider.nutanix.com/v1"`},
+		},
+		{
+			name: "unknown fields",
+			input: `{
+				"prismCentral": {"address": "pc.example.com", "credentialRef": {"kind": "Secret", "name": "nutanix-creds"}},
+				"ignoredNodeIps": ["10.0.0.1"],
+				"enableCustomLabelling": true,
+				"providerIDFormat": "Long"
+			}`,
+			wantErrs: []string{
+				`unknown field "ignoredNodeIps"`,
+				`unknown field "enableCustomLabelling"`,
+				`providerIDFormat: Unsupported value: "Long"`,
+			},
+		},
+		{
+			name: "unknown fields in YAML",
+			input: `
+prismCentral:
+  address: pc.example.com
+  credentialRef:
+    name: nutanix-creds
+  user: admin
+topologyDiscovery:
+  topologyCategory:
+    zoneCategory: zone
+`,
+			wantErrs: []string{
+				`unknown field "prismCentral.user"`,
+				`unknown field "topologyDiscovery.topologyCategory"`,
+			},
+		},
+		{
+			name:     "duplicate JSON keys",
+			input:    `{"enableCustomLabeling": true, "enableCustomLabeling": false}`,
+			wantErrs: []string{`duplicate field "enableCustomLabeling"`},
+		},
+		{
+			name:     "duplicate YAML keys",
+			input:    "enableCustomLabeling: true\nenableCustomLabeling: false\n",
+			wantErrs: []string{"already set in map"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewConfigFromBytes([]byte(tt.input))
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("NewConfigFromBytes() error = %v, want none", err)
+				}
+				if !equality.Semantic.DeepEqual(got, tt.want) {
+					t.Errorf("NewConfigFromBytes() = %+v, want %+v", got, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("NewConfigFromBytes() error = nil, want %q", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("NewConfigFromBytes() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
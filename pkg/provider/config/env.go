@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+)
+
+// Environment variables overriding fields of the config. A variable which is set takes precedence
+// over the config file, even if it is set to an empty value, and the defaults only apply to fields
+// which are set by neither. The Prism and credential variables only override prismCentral, and are
+// rejected if the config lists prismCentrals, as they could not tell which entry to override.
+const (
+	// PrismAddressEnv overrides prismCentral.address
+	PrismAddressEnv = "NUTANIX_PRISM_ADDRESS"
+	// PrismPortEnv overrides prismCentral.port
+	PrismPortEnv = "NUTANIX_PRISM_PORT"
+	// PrismInsecureEnv overrides prismCentral.insecure
+	PrismInsecureEnv = "NUTANIX_PRISM_INSECURE"
+	// CredentialSecretNameEnv overrides prismCentral.credentialRef.name, the kind of the reference is
+	// set to Secret if the config file does not reference credentials
+	CredentialSecretNameEnv = "NUTANIX_CREDENTIAL_SECRET_NAME"
+	// CredentialSecretNamespaceEnv overrides prismCentral.credentialRef.namespace
+	CredentialSecretNamespaceEnv = "NUTANIX_CREDENTIAL_SECRET_NAMESPACE"
+	// TopologyTypeEnv overrides topologyDiscovery.type
+	TopologyTypeEnv = "NUTANIX_TOPOLOGY_TYPE"
+	// TopologyZoneCategoryEnv overrides topologyDiscovery.topologyCategories.zoneCategory
+	TopologyZoneCategoryEnv = "NUTANIX_TOPOLOGY_ZONE_CATEGORY"
+	// TopologyRegionCategoryEnv overrides topologyDiscovery.topologyCategories.regionCategory
+	TopologyRegionCategoryEnv = "NUTANIX_TOPOLOGY_REGION_CATEGORY"
+	// EnableCustomLabelingEnv overrides enableCustomLabeling
+	EnableCustomLabelingEnv = "NUTANIX_ENABLE_CUSTOM_LABELING"
+	// IgnoredNodeIPsEnv overrides ignoredNodeIPs with a comma separated list
+	IgnoredNodeIPsEnv = "NUTANIX_IGNORED_NODE_IPS"
+)
+
+// prismCentralEnvs are the variables overriding fields of prismCentral.
+var prismCentralEnvs = []string{PrismAddressEnv, PrismPortEnv, PrismInsecureEnv, CredentialSecretNameEnv, CredentialSecretNamespaceEnv}
+
+// applyEnvOverrides sets the fields of the config overridden by the environment variables returned by
+// lookupEnv, and returns an error for each variable which cannot be parsed or does not apply.
+func (c *Config) applyEnvOverrides(lookupEnv func(string) (string, bool)) []error {
+	var errs []error
+	if len(c.PrismCentrals) > 0 {
+		for _, env := range prismCentralEnvs {
+			if _, ok := lookupEnv(env); ok {
+				errs = append(errs, fmt.Errorf("%s cannot be used with prismCentrals, set the field of the prismCentrals entry in the config file instead", env))
+			}
+		}
+	} else {
+		errs = append(errs, c.applyPrismCentralEnvOverrides(lookupEnv)...)
+	}
+	if value, ok := lookupEnv(TopologyTypeEnv); ok {
+		c.TopologyDiscovery.Type = TopologyDiscoveryType(value)
+	}
+	if value, ok := lookupEnv(TopologyZoneCategoryEnv); ok {
+		c.topologyCategories().ZoneCategory = value
+	}
+	if value, ok := lookupEnv(TopologyRegionCategoryEnv); ok {
+		c.topologyCategories().RegionCategory = value
+	}
+	if value, ok := lookupEnv(EnableCustomLabelingEnv); ok {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: must be true or false", EnableCustomLabelingEnv, value))
+		}
+		c.EnableCustomLabeling = enabled
+	}
+	if value, ok := lookupEnv(IgnoredNodeIPsEnv); ok {
+		c.IgnoredNodeIPs = nil
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				c.IgnoredNodeIPs = append(c.IgnoredNodeIPs, entry)
+			}
+		}
+	}
+	return errs
+}
+
+// applyPrismCentralEnvOverrides sets the fields of prismCentral overridden by the environment variables.
+func (c *Config) applyPrismCentralEnvOverrides(lookupEnv func(string) (string, bool)) []error {
+	var errs []error
+	if value, ok := lookupEnv(PrismAddressEnv); ok {
+		c.PrismCentral.Address = value
+	}
+	if value, ok := lookupEnv(PrismPortEnv); ok {
+		port, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: must be a port number", PrismPortEnv, value))
+		}
+		c.PrismCentral.Port = int32(port)
+	}
+	if value, ok := lookupEnv(PrismInsecureEnv); ok {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s %q: must be true or false", PrismInsecureEnv, value))
+		}
+		c.PrismCentral.Insecure = insecure
+	}
+	if value, ok := lookupEnv(CredentialSecretNameEnv); ok {
+		c.credentialRef().Name = value
+	}
+	if value, ok := lookupEnv(CredentialSecretNamespaceEnv); ok {
+		c.credentialRef().Namespace = value
+	}
+	return errs
+}
+
+func (c *Config) credentialRef() *credentialTypes.NutanixCredentialReference {
+	if c.PrismCentral.CredentialRef == nil {
+		c.PrismCentral.CredentialRef = &credentialTypes.NutanixCredentialReference{Kind: credentialTypes.SecretKind}
+	}
+	return c.PrismCentral.CredentialRef
+}
+
+func (c *Config) topologyCategories() *TopologyCategories {
+	if c.TopologyDiscovery.TopologyCategories == nil {
+		c.TopologyDiscovery.TopologyCategories = &TopologyCategories{}
+	}
+	return c.TopologyDiscovery.TopologyCategories
+}
@@ -0,0 +1,461 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	"go4.org/netipx"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Validate returns all problems of the config, each prefixed with the JSON path of the offending
+// field. Unset fields which have a default are valid.
+func (c *Config) Validate() error {
+	var errs field.ErrorList
+	errs = append(errs, validatePrismCentrals(c.PrismCentral, c.PrismCentrals)...)
+	errs = append(errs, validateTopologyDiscovery(c.TopologyDiscovery, field.NewPath("topologyDiscovery"))...)
+	errs = append(errs, validateProviderIDFormat(c.ProviderIDFormat, field.NewPath("providerIDFormat"))...)
+	errs = append(errs, validateVMLookup(c.VMLookup, field.NewPath("vmLookup"))...)
+	errs = append(errs, validateVMPowerStates(c.VMPowerStates, field.NewPath("vmPowerStates"))...)
+	errs = append(errs, validateIPList(c.IgnoredNodeIPs, field.NewPath("ignoredNodeIPs"))...)
+	errs = append(errs, validateNodeIPFamilies(c.NodeIPFamilies, field.NewPath("nodeIPFamilies"))...)
+	errs = append(errs, validateNodeAddressRules(c.NodeAddressRules, field.NewPath("nodeAddressRules"))...)
+	errs = append(errs, validateInstanceTypes(c.InstanceTypes, field.NewPath("instanceTypes"))...)
+	errs = append(errs, validateCategoryLabels(c.CategoryLabels, field.NewPath("categoryLabels"))...)
+	errs = append(errs, validatePrismCache(c.PrismCache, field.NewPath("prismCache"))...)
+	errs = append(errs, validatePrismClient(c.PrismClient, field.NewPath("prismClient"))...)
+	errs = append(errs, validateTracing(c.Tracing, field.NewPath("tracing"))...)
+	errs = append(errs, validateLoadBalancer(c.LoadBalancer, field.NewPath("loadBalancer"))...)
+	if c.Routes != nil && c.Routes.VPCUUID == "" {
+		errs = append(errs, field.Required(field.NewPath("routes", "vpcUUID"), "must be set when route support is enabled"))
+	}
+	return errs.ToAggregate()
+}
+
+// ValidateTracing is exported as the tracing settings can also be given as flags.
+func ValidateTracing(tracing *Tracing) error {
+	return validateTracing(tracing, field.NewPath("tracing")).ToAggregate()
+}
+
+// ParseIPRange parses an entry of an IP list, which is a single IP, a CIDR prefix or an IP range
+// such as 10.0.0.10-10.0.0.20.
+func ParseIPRange(entry string) (netipx.IPRange, error) {
+	switch {
+	case strings.Contains(entry, "-"):
+		return netipx.ParseIPRange(entry)
+	case strings.Contains(entry, "/"):
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return netipx.IPRange{}, err
+		}
+		return netipx.RangeOfPrefix(prefix), nil
+	default:
+		ip, err := netip.ParseAddr(entry)
+		if err != nil {
+			return netipx.IPRange{}, err
+		}
+		return netipx.IPRangeFrom(ip, ip), nil
+	}
+}
+
+func validateIPList(entries []string, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, entry := range entries {
+		if _, err := ParseIPRange(entry); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Index(i), entry, "must be an IP, a CIDR prefix or an IP range: "+err.Error()))
+		}
+	}
+	return errs
+}
+
+func validatePrismCentrals(pc credentialTypes.NutanixPrismEndpoint, pcs []PrismCentralEndpoint) field.ErrorList {
+	if len(pcs) == 0 {
+		return validatePrismCentral(pc, field.NewPath("prismCentral"))
+	}
+	var errs field.ErrorList
+	if pc.Address != "" || pc.CredentialRef != nil {
+		errs = append(errs, field.Forbidden(field.NewPath("prismCentral"), "must not be set together with prismCentrals"))
+	}
+	fldPath := field.NewPath("prismCentrals")
+	names := sets.New[string]()
+	for i, endpoint := range pcs {
+		namePath := fldPath.Index(i).Child("name")
+		switch {
+		case endpoint.Name == "":
+			errs = append(errs, field.Required(namePath, ""))
+		case names.Has(endpoint.Name):
+			errs = append(errs, field.Duplicate(namePath, endpoint.Name))
+		default:
+			for _, msg := range validation.IsDNS1123Label(endpoint.Name) {
+				errs = append(errs, field.Invalid(namePath, endpoint.Name, msg))
+			}
+		}
+		names.Insert(endpoint.Name)
+		errs = append(errs, validatePrismCentral(endpoint.NutanixPrismEndpoint, fldPath.Index(i))...)
+	}
+	return errs
+}
+
+func validatePrismCentral(pc credentialTypes.NutanixPrismEndpoint, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if pc.Address == "" {
+		errs = append(errs, field.Required(fldPath.Child("address"), ""))
+	} else if strings.Contains(pc.Address, "://") {
+		errs = append(errs, field.Invalid(fldPath.Child("address"), pc.Address, "must be a host name or IP without a scheme"))
+	}
+	if pc.Port != 0 {
+		for _, msg := range validation.IsValidPortNum(int(pc.Port)) {
+			errs = append(errs, field.Invalid(fldPath.Child("port"), pc.Port, msg))
+		}
+	}
+
+	credentialRef := pc.CredentialRef
+	credentialRefPath := fldPath.Child("credentialRef")
+	if credentialRef == nil {
+		errs = append(errs, field.Required(credentialRefPath, ""))
+	} else {
+		if credentialRef.Kind != credentialTypes.SecretKind {
+			errs = append(errs, field.NotSupported(credentialRefPath.Child("kind"), credentialRef.Kind, []credentialTypes.NutanixCredentialKind{credentialTypes.SecretKind}))
+		}
+		errs = append(errs, validateObjectReference(credentialRef.Name, credentialRef.Namespace, credentialRefPath)...)
+	}
+
+	trustBundle := pc.AdditionalTrustBundle
+	trustBundlePath := fldPath.Child("additionalTrustBundle")
+	if trustBundle != nil {
+		switch trustBundle.Kind {
+		case credentialTypes.NutanixTrustBundleKindString:
+			if trustBundle.Data == "" {
+				errs = append(errs, field.Required(trustBundlePath.Child("data"), "must be set when using trust bundle kind: "+string(trustBundle.Kind)))
+			}
+		case credentialTypes.NutanixTrustBundleKindConfigMap:
+			errs = append(errs, validateObjectReference(trustBundle.Name, trustBundle.Namespace, trustBundlePath)...)
+		default:
+			errs = append(errs, field.NotSupported(trustBundlePath.Child("kind"), trustBundle.Kind, []credentialTypes.NutanixTrustBundleKind{
+				credentialTypes.NutanixTrustBundleKindString, credentialTypes.NutanixTrustBundleKindConfigMap,
+			}))
+		}
+	}
+	return errs
+}
+
+// validateObjectReference validates a reference to an object in the CCM namespace, or in the given
+// namespace if set.
+func validateObjectReference(name string, namespace string, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if name == "" {
+		errs = append(errs, field.Required(fldPath.Child("name"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			errs = append(errs, field.Invalid(fldPath.Child("name"), name, msg))
+		}
+	}
+	if namespace != "" {
+		for _, msg := range validation.IsDNS1123Label(namespace) {
+			errs = append(errs, field.Invalid(fldPath.Child("namespace"), namespace, msg))
+		}
+	}
+	return errs
+}
+
+func validateProviderIDFormat(format ProviderIDFormat, fldPath *field.Path) field.ErrorList {
+	switch format {
+	case "", UUIDProviderIDFormat, QualifiedProviderIDFormat:
+		return nil
+	}
+	return field.ErrorList{field.NotSupported(fldPath, format, []ProviderIDFormat{UUIDProviderIDFormat, QualifiedProviderIDFormat})}
+}
+
+func validateVMPowerStates(vmPowerStates map[string]NodeState, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	powerStates := []string{"ON", "OFF", "PAUSED", "UNDETERMINED"}
+	for _, powerState := range sets.List(sets.KeySet(vmPowerStates)) {
+		if !slices.Contains(powerStates, powerState) {
+			errs = append(errs, field.NotSupported(fldPath.Key(powerState), powerState, powerStates))
+		}
+		switch state := vmPowerStates[powerState]; state {
+		case RunningNodeState, ShutdownNodeState, UnknownNodeState:
+		default:
+			errs = append(errs, field.NotSupported(fldPath.Key(powerState), state,
+				[]NodeState{RunningNodeState, ShutdownNodeState, UnknownNodeState}))
+		}
+	}
+	return errs
+}
+
+func validateVMLookup(vmLookup *VMLookup, fldPath *field.Path) field.ErrorList {
+	if vmLookup == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if len(vmLookup.Fallbacks) == 0 {
+		errs = append(errs, field.Required(fldPath.Child("fallbacks"), ""))
+	}
+	strategies := sets.New[VMLookupStrategy]()
+	for i, strategy := range vmLookup.Fallbacks {
+		switch {
+		case strategies.Has(strategy):
+			errs = append(errs, field.Duplicate(fldPath.Child("fallbacks").Index(i), strategy))
+		case strategy != NameVMLookupStrategy && strategy != InternalIPVMLookupStrategy && strategy != AnnotationVMLookupStrategy:
+			errs = append(errs, field.NotSupported(fldPath.Child("fallbacks").Index(i), strategy,
+				[]VMLookupStrategy{NameVMLookupStrategy, InternalIPVMLookupStrategy, AnnotationVMLookupStrategy}))
+		}
+		strategies.Insert(strategy)
+	}
+	if vmLookup.Annotation != "" {
+		for _, msg := range validation.IsQualifiedName(vmLookup.Annotation) {
+			errs = append(errs, field.Invalid(fldPath.Child("annotation"), vmLookup.Annotation, msg))
+		}
+	}
+	return errs
+}
+
+func validateTopologyDiscovery(topologyDiscovery TopologyDiscovery, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	switch topologyDiscovery.Type {
+	case "", PrismTopologyDiscoveryType:
+	case CategoriesTopologyDiscoveryType:
+		if topologyDiscovery.TopologyCategories == nil {
+			errs = append(errs, field.Required(fldPath.Child("topologyCategories"),
+				"must be set when using topology discovery type: "+string(CategoriesTopologyDiscoveryType)))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("type"), topologyDiscovery.Type,
+			[]TopologyDiscoveryType{PrismTopologyDiscoveryType, CategoriesTopologyDiscoveryType}))
+	}
+	return errs
+}
+
+func validateLoadBalancer(lb *LoadBalancer, fldPath *field.Path) field.ErrorList {
+	if lb == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	switch lb.Type {
+	case "", IPPoolLoadBalancerType:
+		if len(lb.IPPools) == 0 {
+			errs = append(errs, field.Required(fldPath.Child("ipPools"),
+				"must contain at least one pool when using load balancer type: "+string(IPPoolLoadBalancerType)))
+		}
+	case FlowVPCLoadBalancerType:
+		flowVPCPath := fldPath.Child("flowVPC")
+		if lb.FlowVPC == nil {
+			errs = append(errs, field.Required(flowVPCPath, "must be set when using load balancer type: "+string(FlowVPCLoadBalancerType)))
+			break
+		}
+		if lb.FlowVPC.VPCUUID == "" {
+			errs = append(errs, field.Required(flowVPCPath.Child("vpcUUID"), ""))
+		}
+		if lb.FlowVPC.VIPSubnetUUID == "" {
+			errs = append(errs, field.Required(flowVPCPath.Child("vipSubnetUUID"), ""))
+		}
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("type"), lb.Type,
+			[]LoadBalancerType{IPPoolLoadBalancerType, FlowVPCLoadBalancerType}))
+	}
+	poolNames := make(map[string]struct{}, len(lb.IPPools))
+	for i, pool := range lb.IPPools {
+		poolPath := fldPath.Child("ipPools").Index(i)
+		if pool.Name == "" {
+			errs = append(errs, field.Required(poolPath.Child("name"), ""))
+		} else if _, ok := poolNames[pool.Name]; ok {
+			errs = append(errs, field.Duplicate(poolPath.Child("name"), pool.Name))
+		}
+		poolNames[pool.Name] = struct{}{}
+		if len(pool.Addresses) == 0 {
+			errs = append(errs, field.Required(poolPath.Child("addresses"), ""))
+		}
+		errs = append(errs, validateIPList(pool.Addresses, poolPath.Child("addresses"))...)
+	}
+	return errs
+}
+
+func validateNodeIPFamilies(families []IPFamily, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if len(families) > 2 {
+		errs = append(errs, field.TooMany(fldPath, len(families), 2))
+	}
+	seen := make(map[IPFamily]struct{}, len(families))
+	for i, family := range families {
+		if family != IPv4IPFamily && family != IPv6IPFamily {
+			errs = append(errs, field.NotSupported(fldPath.Index(i), family, []IPFamily{IPv4IPFamily, IPv6IPFamily}))
+		}
+		if _, ok := seen[family]; ok {
+			errs = append(errs, field.Duplicate(fldPath.Index(i), family))
+		}
+		seen[family] = struct{}{}
+	}
+	return errs
+}
+
+func validateNodeAddressRules(rules []NodeAddressRule, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	for i, rule := range rules {
+		rulePath := fldPath.Index(i)
+		if rule.Type != InternalIPNodeAddressType && rule.Type != ExternalIPNodeAddressType {
+			errs = append(errs, field.NotSupported(rulePath.Child("type"), rule.Type,
+				[]NodeAddressType{InternalIPNodeAddressType, ExternalIPNodeAddressType}))
+		}
+		if len(rule.CIDRs) == 0 && len(rule.SubnetNames) == 0 && len(rule.SubnetUUIDs) == 0 {
+			errs = append(errs, field.Required(rulePath, "must set at least one of cidrs, subnetNames or subnetUUIDs"))
+		}
+		errs = append(errs, validateIPList(rule.CIDRs, rulePath.Child("cidrs"))...)
+	}
+	return errs
+}
+
+func validateInstanceTypes(instanceTypes *InstanceTypes, fldPath *field.Path) field.ErrorList {
+	if instanceTypes == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	names := make(map[string]struct{}, len(instanceTypes.Classes))
+	for i, class := range instanceTypes.Classes {
+		classPath := fldPath.Child("classes").Index(i)
+		if class.Name == "" {
+			errs = append(errs, field.Required(classPath.Child("name"), ""))
+		} else if msgs := validation.IsValidLabelValue(class.Name); len(msgs) > 0 {
+			errs = append(errs, field.Invalid(classPath.Child("name"), class.Name, strings.Join(msgs, ", ")))
+		} else if _, ok := names[class.Name]; ok {
+			errs = append(errs, field.Duplicate(classPath.Child("name"), class.Name))
+		}
+		names[class.Name] = struct{}{}
+		if class.VCPUs < 0 {
+			errs = append(errs, field.Invalid(classPath.Child("vcpus"), class.VCPUs, "must not be negative"))
+		}
+		if class.MemoryGiB < 0 {
+			errs = append(errs, field.Invalid(classPath.Child("memoryGiB"), class.MemoryGiB, "must not be negative"))
+		}
+		if class.GPUs != nil && *class.GPUs < 0 {
+			errs = append(errs, field.Invalid(classPath.Child("gpus"), *class.GPUs, "must not be negative"))
+		}
+	}
+	return errs
+}
+
+func validateCategoryLabels(categoryLabels *CategoryLabels, fldPath *field.Path) field.ErrorList {
+	if categoryLabels == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	if len(categoryLabels.KeyPrefixes) == 0 && len(categoryLabels.Keys) == 0 {
+		errs = append(errs, field.Required(fldPath, "must set at least one of keyPrefixes or keys"))
+	}
+	if categoryLabels.Domain != "" {
+		for _, msg := range validation.IsDNS1123Subdomain(categoryLabels.Domain) {
+			errs = append(errs, field.Invalid(fldPath.Child("domain"), categoryLabels.Domain, msg))
+		}
+	}
+	return errs
+}
+
+func validatePrismCache(prismCache PrismCache, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if prismCache.MaxEntries < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxEntries"), prismCache.MaxEntries, "must not be negative"))
+	}
+	for _, ttl := range []struct {
+		name  string
+		value metav1.Duration
+	}{
+		{"vmTTL", prismCache.VMTTL},
+		{"clusterTTL", prismCache.ClusterTTL},
+		{"hostTTL", prismCache.HostTTL},
+		{"categoryTTL", prismCache.CategoryTTL},
+		{"subnetTTL", prismCache.SubnetTTL},
+		{"notFoundTTL", prismCache.NotFoundTTL},
+	} {
+		errs = append(errs, validateNonNegativeDuration(ttl.value, fldPath.Child(ttl.name))...)
+	}
+	return errs
+}
+
+func validatePrismClient(prismClient PrismClient, fldPath *field.Path) field.ErrorList {
+	var errs field.ErrorList
+	if prismClient.QPS < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("qps"), prismClient.QPS, "must not be negative"))
+	}
+	if prismClient.Burst < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("burst"), prismClient.Burst, "must not be negative"))
+	}
+
+	retry := prismClient.Retry
+	retryPath := fldPath.Child("retry")
+	if retry.MaxAttempts < 0 {
+		errs = append(errs, field.Invalid(retryPath.Child("maxAttempts"), retry.MaxAttempts, "must not be negative"))
+	}
+	errs = append(errs, validateNonNegativeDuration(retry.InitialBackoff, retryPath.Child("initialBackoff"))...)
+	errs = append(errs, validateNonNegativeDuration(retry.MaxBackoff, retryPath.Child("maxBackoff"))...)
+	if retry.InitialBackoff.Duration > 0 && retry.MaxBackoff.Duration > 0 && retry.MaxBackoff.Duration < retry.InitialBackoff.Duration {
+		errs = append(errs, field.Invalid(retryPath.Child("maxBackoff"), retry.MaxBackoff.Duration.String(), "must not be less than initialBackoff"))
+	}
+	if retry.Jitter != nil && *retry.Jitter < 0 {
+		errs = append(errs, field.Invalid(retryPath.Child("jitter"), *retry.Jitter, "must not be negative"))
+	}
+	for i, class := range retry.RetryOn {
+		switch class {
+		case ThrottledPrismErrorClass, UnavailablePrismErrorClass, UnauthorizedPrismErrorClass:
+		default:
+			errs = append(errs, field.NotSupported(retryPath.Child("retryOn").Index(i), class,
+				[]PrismErrorClass{ThrottledPrismErrorClass, UnavailablePrismErrorClass, UnauthorizedPrismErrorClass}))
+		}
+	}
+
+	breakerPath := fldPath.Child("circuitBreaker")
+	if prismClient.CircuitBreaker.FailureThreshold < 0 {
+		errs = append(errs, field.Invalid(breakerPath.Child("failureThreshold"), prismClient.CircuitBreaker.FailureThreshold, "must not be negative"))
+	}
+	errs = append(errs, validateNonNegativeDuration(prismClient.CircuitBreaker.OpenDuration, breakerPath.Child("openDuration"))...)
+	return errs
+}
+
+func validateTracing(tracing *Tracing, fldPath *field.Path) field.ErrorList {
+	if tracing == nil {
+		return nil
+	}
+	var errs field.ErrorList
+	switch tracing.Exporter {
+	case OTLPTracingExporter, StdoutTracingExporter:
+	case FileTracingExporter:
+		if tracing.FilePath == "" {
+			errs = append(errs, field.Required(fldPath.Child("filePath"), "must be set when using tracing exporter: "+string(FileTracingExporter)))
+		}
+	case "":
+		errs = append(errs, field.Required(fldPath.Child("exporter"), "must be set when tracing is enabled"))
+	default:
+		errs = append(errs, field.NotSupported(fldPath.Child("exporter"), tracing.Exporter,
+			[]TracingExporter{OTLPTracingExporter, StdoutTracingExporter, FileTracingExporter}))
+	}
+	if ratio := tracing.SamplingRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		errs = append(errs, field.Invalid(fldPath.Child("samplingRatio"), *ratio, "must be between 0 and 1"))
+	}
+	return errs
+}
+
+func validateNonNegativeDuration(duration metav1.Duration, fldPath *field.Path) field.ErrorList {
+	if duration.Duration < 0 {
+		return field.ErrorList{field.Invalid(fldPath, duration.Duration.String(), "must not be negative")}
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+)
+
+// validConfig returns a config which passes validation, for the test cases to break.
+func validConfig() Config {
+	return Config{
+		PrismCentral: validPrismCentral("pc.example.com"),
+	}
+}
+
+func validPrismCentral(address string) credentialTypes.NutanixPrismEndpoint {
+	return credentialTypes.NutanixPrismEndpoint{
+		Address: address,
+		Port:    DefaultPrismCentralPort,
+		CredentialRef: &credentialTypes.NutanixCredentialReference{
+			Kind: credentialTypes.SecretKind,
+			Name: "nutanix-creds",
+		},
+	}
+}
+
+type validationTest struct {
+	name   string
+	modify func(c *Config)
+	// wantErrs are the expected problems, none if the config is valid
+	wantErrs []string
+}
+
+func runValidationTests(t *testing.T, tests []validationTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.modify(&c)
+			err := c.Validate()
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Config.Validate() error = %v, want none", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Config.Validate() error = nil, want %q", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Config.Validate() error = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePrismCentralEndpoint(t *testing.T) {
+	runValidationTests(t, []validationTest{
+		{
+			name:   "valid prismCentral",
+			modify: func(c *Config) {},
+		},
+		{
+			name:     "missing address",
+			modify:   func(c *Config) { c.PrismCentral.Address = "" },
+			wantErrs: []string{"prismCentral.address: Required value"},
+		},
+		{
+			name:     "address with scheme",
+			modify:   func(c *Config) { c.PrismCentral.Address = "https://pc.example.com" },
+			wantErrs: []string{`prismCentral.address: Invalid value: "https://pc.example.com"`},
+		},
+		{
+			name:     "invalid port",
+			modify:   func(c *Config) { c.PrismCentral.Port = 70000 },
+			wantErrs: []string{"prismCentral.port: Invalid value: 70000"},
+		},
+		{
+			name: "valid prismCentrals",
+			modify: func(c *Config) {
+				c.PrismCentral = credentialTypes.NutanixPrismEndpoint{}
+				c.PrismCentrals = []PrismCentralEndpoint{
+					{Name: "pc-1", NutanixPrismEndpoint: validPrismCentral("pc-1.example.com")},
+					{Name: "pc-2", NutanixPrismEndpoint: validPrismCentral("pc-2.example.com")},
+				}
+			},
+		},
+		{
+			name: "prismCentral together with prismCentrals",
+			modify: func(c *Config) {
+				c.PrismCentrals = []PrismCentralEndpoint{{Name: "pc-1", NutanixPrismEndpoint: validPrismCentral("pc-1.example.com")}}
+			},
+			wantErrs: []string{"prismCentral: Forbidden"},
+		},
+		{
+			name: "invalid prismCentrals names",
+			modify: func(c *Config) {
+				c.PrismCentral = credentialTypes.NutanixPrismEndpoint{}
+				c.PrismCentrals = []PrismCentralEndpoint{
+					{Name: "", NutanixPrismEndpoint: validPrismCentral("pc-1.example.com")},
+					{Name: "PC_2", NutanixPrismEndpoint: validPrismCentral("pc-2.example.com")},
+					{Name: "pc-3", NutanixPrismEndpoint: validPrismCentral("pc-3.example.com")},
+					{Name: "pc-3", NutanixPrismEndpoint: validPrismCentral("pc-4.example.com")},
+				}
+			},
+			wantErrs: []string{
+				"prismCentrals[0].name: Required value",
+				`prismCentrals[1].name: Invalid value: "PC_2"`,
+				`prismCentrals[3].name: Duplicate value: "pc-3"`,
+			},
+		},
+		{
+			name: "invalid prismCentrals endpoint",
+			modify: func(c *Config) {
+				c.PrismCentral = credentialTypes.NutanixPrismEndpoint{}
+				c.PrismCentrals = []PrismCentralEndpoint{
+					{Name: "pc-1", NutanixPrismEndpoint: validPrismCentral("pc-1.example.com")},
+					{Name: "pc-2", NutanixPrismEndpoint: validPrismCentral("")},
+				}
+			},
+			wantErrs: []string{"prismCentrals[1].address: Required value"},
+		},
+		{
+			name: "invalid trust bundle",
+			modify: func(c *Config) {
+				c.PrismCentral.AdditionalTrustBundle = &credentialTypes.NutanixTrustBundleReference{Kind: credentialTypes.NutanixTrustBundleKindString}
+			},
+			wantErrs: []string{"prismCentral.additionalTrustBundle.data: Required value"},
+		},
+	})
+}
+
+func TestValidateCredentialRef(t *testing.T) {
+	runValidationTests(t, []validationTest{
+		{
+			name:     "missing credentialRef",
+			modify:   func(c *Config) { c.PrismCentral.CredentialRef = nil },
+			wantErrs: []string{"prismCentral.credentialRef: Required value"},
+		},
+		{
+			name:     "unsupported kind",
+			modify:   func(c *Config) { c.PrismCentral.CredentialRef.Kind = "secret" },
+			wantErrs: []string{`prismCentral.credentialRef.kind: Unsupported value: "secret"`},
+		},
+		{
+			name:     "missing name",
+			modify:   func(c *Config) { c.PrismCentral.CredentialRef.Name = "" },
+			wantErrs: []string{"prismCentral.credentialRef.name: Required value"},
+		},
+		{
+			name:     "invalid name",
+			modify:   func(c *Config) { c.PrismCentral.CredentialRef.Name = "Nutanix_Creds" },
+			wantErrs: []string{`prismCentral.credentialRef.name: Invalid value: "Nutanix_Creds"`},
+		},
+		{
+			name:   "valid namespace",
+			modify: func(c *Config) { c.PrismCentral.CredentialRef.Namespace = "kube-system" },
+		},
+		{
+			name:     "invalid namespace",
+			modify:   func(c *Config) { c.PrismCentral.CredentialRef.Namespace = "kube.system" },
+			wantErrs: []string{`prismCentral.credentialRef.namespace: Invalid value: "kube.system"`},
+		},
+		{
+			name: "missing credentialRef of prismCentrals",
+			modify: func(c *Config) {
+				endpoint := validPrismCentral("pc-1.example.com")
+				endpoint.CredentialRef = nil
+				c.PrismCentral = credentialTypes.NutanixPrismEndpoint{}
+				c.PrismCentrals = []PrismCentralEndpoint{{Name: "pc-1", NutanixPrismEndpoint: endpoint}}
+			},
+			wantErrs: []string{"prismCentrals[0].credentialRef: Required value"},
+		},
+	})
+}
+
+func TestValidateTopologyDiscovery(t *testing.T) {
+	runValidationTests(t, []validationTest{
+		{
+			name:   "default type",
+			modify: func(c *Config) { c.TopologyDiscovery = TopologyDiscovery{} },
+		},
+		{
+			name:   "Prism type",
+			modify: func(c *Config) { c.TopologyDiscovery = TopologyDiscovery{Type: PrismTopologyDiscoveryType} },
+		},
+		{
+			name: "Categories type",
+			modify: func(c *Config) {
+				c.TopologyDiscovery = TopologyDiscovery{
+					Type:               CategoriesTopologyDiscoveryType,
+					TopologyCategories: &TopologyCategories{ZoneCategory: "zone", RegionCategory: "region"},
+				}
+			},
+		},
+		{
+			name:     "Categories type without categories",
+			modify:   func(c *Config) { c.TopologyDiscovery = TopologyDiscovery{Type: CategoriesTopologyDiscoveryType} },
+			wantErrs: []string{"topologyDiscovery.topologyCategories: Required value"},
+		},
+		{
+			name:     "unsupported type",
+			modify:   func(c *Config) { c.TopologyDiscovery = TopologyDiscovery{Type: "Zones"} },
+			wantErrs: []string{`topologyDiscovery.type: Unsupported value: "Zones"`},
+		},
+	})
+}
+
+func TestValidateIPLists(t *testing.T) {
+	runValidationTests(t, []validationTest{
+		{
+			name: "valid entries",
+			modify: func(c *Config) {
+				c.IgnoredNodeIPs = []string{"10.0.0.1", "10.0.1.0/24", "10.0.2.10-10.0.2.20", "fd00::1"}
+			},
+		},
+		{
+			name:     "invalid IP",
+			modify:   func(c *Config) { c.IgnoredNodeIPs = []string{"10.0.0.1", "10.0.0.300"} },
+			wantErrs: []string{`ignoredNodeIPs[1]: Invalid value: "10.0.0.300"`},
+		},
+		{
+			name:     "invalid prefix",
+			modify:   func(c *Config) { c.IgnoredNodeIPs = []string{"10.0.0.0/33"} },
+			wantErrs: []string{`ignoredNodeIPs[0]: Invalid value: "10.0.0.0/33"`},
+		},
+		{
+			name:     "invalid range",
+			modify:   func(c *Config) { c.IgnoredNodeIPs = []string{"10.0.0.20-10.0.0.10"} },
+			wantErrs: []string{`ignoredNodeIPs[0]: Invalid value: "10.0.0.20-10.0.0.10"`},
+		},
+		{
+			name: "invalid node address rule CIDR",
+			modify: func(c *Config) {
+				c.NodeAddressRules = []NodeAddressRule{{Type: ExternalIPNodeAddressType, CIDRs: []string{"10.0.0.0/8", "10.0.0.300/8"}}}
+			},
+			wantErrs: []string{`nodeAddressRules[0].cidrs[1]: Invalid value: "10.0.0.300/8"`},
+		},
+		{
+			name: "invalid load balancer pool address",
+			modify: func(c *Config) {
+				c.LoadBalancer = &LoadBalancer{IPPools: []IPPool{{Name: "pool", Addresses: []string{"pool.example.com"}}}}
+			},
+			wantErrs: []string{`loadBalancer.ipPools[0].addresses[0]: Invalid value: "pool.example.com"`},
+		},
+	})
+}
+
+func TestValidateReportsAllErrors(t *testing.T) {
+	runValidationTests(t, []validationTest{
+		{
+			name: "several invalid fields",
+			modify: func(c *Config) {
+				c.PrismCentral.Address = ""
+				c.PrismCentral.CredentialRef.Name = ""
+				c.IgnoredNodeIPs = []string{"10.0.0.1", "10.0.0.300"}
+				c.TopologyDiscovery.Type = CategoriesTopologyDiscoveryType
+				c.ProviderIDFormat = "Long"
+			},
+			wantErrs: []string{
+				"prismCentral.address: Required value",
+				"prismCentral.credentialRef.name: Required value",
+				`ignoredNodeIPs[1]: Invalid value: "10.0.0.300"`,
+				"topologyDiscovery.topologyCategories: Required value",
+				`providerIDFormat: Unsupported value: "Long"`,
+			},
+		},
+	})
+}
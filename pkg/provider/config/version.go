@@ -0,0 +1,95 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	klog "k8s.io/klog/v2"
+)
+
+const (
+	// APIVersion is the current version of the config schema
+	APIVersion = "config.cloudprovider.nutanix.com/v1alpha1"
+	// Kind of the config
+	Kind = "NutanixCloudConfig"
+
+	// DefaultPrismCentralPort is the port of Prism Central if none is configured
+	DefaultPrismCentralPort = 9440
+)
+
+// convert converts the config to the current version, returning an error if it is of an unsupported
+// version. Configs without apiVersion and kind use the unversioned format, which was used before
+// the config schema was versioned.
+func (c *Config) convert() error {
+	switch {
+	case c.APIVersion == "" && c.Kind == "":
+		c.convertUnversioned()
+	case c.APIVersion != APIVersion:
+		return fmt.Errorf("unsupported config apiVersion %q, supported versions: %s", c.APIVersion, APIVersion)
+	case c.Kind != Kind:
+		return fmt.Errorf("unsupported config kind %q, expected %s", c.Kind, Kind)
+	}
+	return nil
+}
+
+func (c *Config) convertUnversioned() {
+	klog.Warningf("the cloud config does not set apiVersion and kind, converting it from the deprecated unversioned format. "+
+		"Set apiVersion: %s and kind: %s to use the current version", APIVersion, Kind)
+	c.APIVersion = APIVersion
+	c.Kind = Kind
+	// The unversioned format did not check the credential kind, and its manifests used "secret"
+	if ref := c.PrismCentral.CredentialRef; ref != nil && strings.EqualFold(string(ref.Kind), string(credentialTypes.SecretKind)) {
+		ref.Kind = credentialTypes.SecretKind
+	}
+}
+
+// SetDefaults sets the default of every unset field of the config which has one. Fields whose
+// default depends on the state of the cloud provider, such as the Prism cache and client settings,
+// are defaulted by the cloud provider.
+func SetDefaults(c *Config) {
+	if c.APIVersion == "" && c.Kind == "" {
+		c.APIVersion = APIVersion
+		c.Kind = Kind
+	}
+	if len(c.PrismCentrals) == 0 {
+		setPrismCentralDefaults(&c.PrismCentral)
+	}
+	for i := range c.PrismCentrals {
+		setPrismCentralDefaults(&c.PrismCentrals[i].NutanixPrismEndpoint)
+	}
+	if c.ProviderIDFormat == "" {
+		c.ProviderIDFormat = UUIDProviderIDFormat
+	}
+	if c.TopologyDiscovery.Type == "" {
+		c.TopologyDiscovery.Type = PrismTopologyDiscoveryType
+	}
+	if c.LoadBalancer != nil && c.LoadBalancer.Type == "" {
+		c.LoadBalancer.Type = IPPoolLoadBalancerType
+	}
+}
+
+func setPrismCentralDefaults(pc *credentialTypes.NutanixPrismEndpoint) {
+	if pc.Port == 0 {
+		pc.Port = DefaultPrismCentralPort
+	}
+	if ref := pc.CredentialRef; ref != nil && ref.Kind == "" {
+		ref.Kind = credentialTypes.SecretKind
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConvert(t *testing.T) {
+	withCredentialKind := func(c Config, kind credentialTypes.NutanixCredentialKind) Config {
+		c.PrismCentral.CredentialRef.Kind = kind
+		return c
+	}
+	withTypeMeta := func(c Config, apiVersion string, kind string) Config {
+		c.TypeMeta = metav1.TypeMeta{APIVersion: apiVersion, Kind: kind}
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		config  Config
+		want    Config
+		wantErr string
+	}{
+		{
+			name:   "unversioned",
+			config: validConfig(),
+			want:   withTypeMeta(validConfig(), APIVersion, Kind),
+		},
+		{
+			name:   "unversioned with lower case credential kind",
+			config: withCredentialKind(validConfig(), "secret"),
+			want:   withTypeMeta(validConfig(), APIVersion, Kind),
+		},
+		{
+			name:   "unversioned with upper case credential kind",
+			config: withCredentialKind(validConfig(), "SECRET"),
+			want:   withTypeMeta(validConfig(), APIVersion, Kind),
+		},
+		{
+			name:   "unversioned with unknown credential kind",
+			config: withCredentialKind(validConfig(), "ConfigMap"),
+			want:   withCredentialKind(withTypeMeta(validConfig(), APIVersion, Kind), "ConfigMap"),
+		},
+		{
+			name:   "versioned",
+			config: withTypeMeta(validConfig(), APIVersion, Kind),
+			want:   withTypeMeta(validConfig(), APIVersion, Kind),
+		},
+		{
+			name:   "versioned with lower case credential kind",
+			config: withCredentialKind(withTypeMeta(validConfig(), APIVersion, Kind), "secret"),
+			want:   withCredentialKind(withTypeMeta(validConfig(), APIVersion, Kind), "secret"),
+		},
+		{
+			name:    "unknown apiVersion",
+			config:  withTypeMeta(validConfig(), "config.cloudprovider.nutanix.com/v1", Kind),
+			wantErr: `unsupported config apiVersion "config.cloudprovider.nutanix.com/v1", supported versions: ` + APIVersion,
+		},
+		{
+			name:    "apiVersion without kind",
+			config:  withTypeMeta(validConfig(), APIVersion, ""),
+			wantErr: `unsupported config kind "", expected ` + Kind,
+		},
+		{
+			name:    "unknown kind",
+			config:  withTypeMeta(validConfig(), APIVersion, "Config"),
+			wantErr: `unsupported config kind "Config", expected ` + Kind,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			err := c.convert()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("Config.convert() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Config.convert() error = %v, want none", err)
+			}
+			if !equality.Semantic.DeepEqual(c, tt.want) {
+				t.Errorf("Config.convert() = %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	endpoint := func(address string, port int32, kind credentialTypes.NutanixCredentialKind) credentialTypes.NutanixPrismEndpoint {
+		return credentialTypes.NutanixPrismEndpoint{
+			Address:       address,
+			Port:          port,
+			CredentialRef: &credentialTypes.NutanixCredentialReference{Kind: kind, Name: "nutanix-creds"},
+		}
+	}
+	typeMeta := metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   Config
+	}{
+		{
+			name:   "unset fields",
+			config: Config{PrismCentral: endpoint("pc.example.com", 0, "")},
+			want: Config{
+				TypeMeta:          typeMeta,
+				PrismCentral:      endpoint("pc.example.com", DefaultPrismCentralPort, credentialTypes.SecretKind),
+				TopologyDiscovery: TopologyDiscovery{Type: PrismTopologyDiscoveryType},
+				ProviderIDFormat:  UUIDProviderIDFormat,
+			},
+		},
+		{
+			name: "set fields",
+			config: Config{
+				TypeMeta:          typeMeta,
+				PrismCentral:      endpoint("pc.example.com", 9441, credentialTypes.SecretKind),
+				TopologyDiscovery: TopologyDiscovery{Type: CategoriesTopologyDiscoveryType},
+				ProviderIDFormat:  QualifiedProviderIDFormat,
+				LoadBalancer:      &LoadBalancer{Type: FlowVPCLoadBalancerType},
+			},
+			want: Config{
+				TypeMeta:          typeMeta,
+				PrismCentral:      endpoint("pc.example.com", 9441, credentialTypes.SecretKind),
+				TopologyDiscovery: TopologyDiscovery{Type: CategoriesTopologyDiscoveryType},
+				ProviderIDFormat:  QualifiedProviderIDFormat,
+				LoadBalancer:      &LoadBalancer{Type: FlowVPCLoadBalancerType},
+			},
+		},
+		{
+			name: "load balancer type",
+			config: Config{
+				PrismCentral: endpoint("pc.example.com", 9440, credentialTypes.SecretKind),
+				LoadBalancer: &LoadBalancer{},
+			},
+			want: Config{
+				TypeMeta:          typeMeta,
+				PrismCentral:      endpoint("pc.example.com", 9440, credentialTypes.SecretKind),
+				TopologyDiscovery: TopologyDiscovery{Type: PrismTopologyDiscoveryType},
+				ProviderIDFormat:  UUIDProviderIDFormat,
+				LoadBalancer:      &LoadBalancer{Type: IPPoolLoadBalancerType},
+			},
+		},
+		{
+			name: "prismCentrals",
+			config: Config{
+				PrismCentrals: []PrismCentralEndpoint{
+					{Name: "pc-1", NutanixPrismEndpoint: endpoint("pc-1.example.com", 0, "")},
+					{Name: "pc-2", NutanixPrismEndpoint: endpoint("pc-2.example.com", 9441, "")},
+				},
+			},
+			want: Config{
+				TypeMeta: typeMeta,
+				PrismCentrals: []PrismCentralEndpoint{
+					{Name: "pc-1", NutanixPrismEndpoint: endpoint("pc-1.example.com", DefaultPrismCentralPort, credentialTypes.SecretKind)},
+					{Name: "pc-2", NutanixPrismEndpoint: endpoint("pc-2.example.com", 9441, credentialTypes.SecretKind)},
+				},
+				TopologyDiscovery: TopologyDiscovery{Type: PrismTopologyDiscoveryType},
+				ProviderIDFormat:  UUIDProviderIDFormat,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.config
+			SetDefaults(&c)
+			if !equality.Semantic.DeepEqual(c, tt.want) {
+				t.Errorf("SetDefaults() = %+v, want %+v", c, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// configReloadInterval is how often the config file is checked for changes. The kubelet takes up to
+// a minute to update files of mounted ConfigMaps, so the file is polled rather than watched for
+// events, which are not reliably delivered for the symlinks of ConfigMap volumes.
+const configReloadInterval = 10 * time.Second
+
+// configWatcher reloads the config of the manager whenever the content of the config file changes.
+type configWatcher struct {
+	path    string
+	manager *nutanixManager
+	// content is the content of the config file last read, valid or not
+	content []byte
+}
+
+func newConfigWatcher(path string, manager *nutanixManager) *configWatcher {
+	w := &configWatcher{path: path, manager: manager}
+	// The config file was read before the manager was created, so only later changes are reloaded
+	if content, err := os.ReadFile(path); err == nil {
+		w.content = content
+	}
+	return w
+}
+
+func (w *configWatcher) run(stopCh <-chan struct{}) {
+	klog.Infof("Watching config file %s for changes", w.path) //nolint:typecheck
+	wait.Until(w.reloadIfChanged, configReloadInterval, stopCh)
+}
+
+func (w *configWatcher) reloadIfChanged() {
+	content, err := os.ReadFile(w.path)
+	if err != nil {
+		klog.Errorf("failed to read config file %s: %v", w.path, err) //nolint:typecheck
+		return
+	}
+	if bytes.Equal(content, w.content) {
+		return
+	}
+	w.content = content
+
+	newConfig, err := config.NewConfigFromBytes(content)
+	if err == nil {
+		err = w.manager.reloadConfig(newConfig)
+	}
+	if err != nil {
+		configReloads.WithLabelValues("invalid").Inc()
+		klog.Errorf("rejected invalid config file %s, keeping the last valid config: %v", w.path, err) //nolint:typecheck
+		return
+	}
+	configReloads.WithLabelValues("success").Inc()
+	klog.Infof("Reloaded config file %s", w.path) //nolint:typecheck
+}
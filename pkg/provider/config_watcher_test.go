@@ -0,0 +1,113 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"encoding/json"
+	"net/netip"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test config watcher", func() { //nolint:typecheck
+	var (
+		path    string
+		c       config.Config
+		manager *nutanixManager
+		watcher *configWatcher
+	)
+
+	writeConfig := func(c config.Config) {
+		content, err := json.Marshal(c)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.WriteFile(path, content, 0o600)).To(Succeed())
+	}
+
+	isIgnored := func(ip string) bool {
+		return manager.getNodeSettings().ignoredNodeIPs.Contains(netip.MustParseAddr(ip))
+	}
+
+	reloads := func(result string) float64 {
+		value, err := testutil.GetCounterMetricValue(configReloads.WithLabelValues(result))
+		Expect(err).ToNot(HaveOccurred())
+		return value
+	}
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "nutanix_config.json")
+		c = mock.GenerateMockConfig()
+		c.IgnoredNodeIPs = []string{"10.0.0.1"}
+		writeConfig(c)
+		var err error
+		manager, err = newNutanixManager(c)
+		Expect(err).ToNot(HaveOccurred())
+		watcher = newConfigWatcher(path, manager)
+	})
+
+	It("should apply changes of the config file", func() {
+		successes := reloads("success")
+		c.IgnoredNodeIPs = []string{"10.0.0.2"}
+		c.EnableCustomLabeling = true
+		writeConfig(c)
+
+		watcher.reloadIfChanged()
+		Expect(isIgnored("10.0.0.1")).To(BeFalse())
+		Expect(isIgnored("10.0.0.2")).To(BeTrue())
+		Expect(manager.getConfig().EnableCustomLabeling).To(BeTrue())
+		Expect(reloads("success")).To(Equal(successes + 1))
+	})
+
+	It("should keep the last valid config if the file is invalid", func() {
+		rejections := reloads("invalid")
+		c.IgnoredNodeIPs = []string{"not an IP"}
+		writeConfig(c)
+
+		watcher.reloadIfChanged()
+		Expect(isIgnored("10.0.0.1")).To(BeTrue())
+		Expect(reloads("invalid")).To(Equal(rejections + 1))
+
+		Expect(os.WriteFile(path, []byte("{"), 0o600)).To(Succeed())
+		watcher.reloadIfChanged()
+		Expect(isIgnored("10.0.0.1")).To(BeTrue())
+		Expect(reloads("invalid")).To(Equal(rejections + 2))
+	})
+
+	It("should not reload an unchanged config file", func() {
+		successes := reloads("success")
+		watcher.reloadIfChanged()
+		Expect(reloads("success")).To(Equal(successes))
+	})
+
+	It("should keep the settings which require a restart", func() {
+		c.PrismCache.Disabled = true
+		c.NodeIPFamilies = []config.IPFamily{config.IPv6IPFamily}
+		writeConfig(c)
+
+		watcher.reloadIfChanged()
+		Expect(manager.getConfig().PrismCache.Disabled).To(BeFalse())
+		Expect(manager.nodeIPFamilies()).To(Equal([]config.IPFamily{config.IPv6IPFamily}))
+		Expect(restartRequiredConfigChanges(mock.GenerateMockConfig(), c)).To(Equal([]string{"prismCache"}))
+	})
+})
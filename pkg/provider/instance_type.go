@@ -0,0 +1,96 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+)
+
+const (
+	mebibyte = 1024 * 1024
+	gibibyte = 1024 * mebibyte
+)
+
+// vmShape is the sizing of a VM the instance type is derived from.
+type vmShape struct {
+	vcpus       int
+	memoryBytes int64
+	gpus        int
+}
+
+// getInstanceType returns the instance class matching the VM shape, or a name derived from the
+// shape if no class matches. The static instance type is returned if instance types are not
+// configured or the shape of the VM cannot be determined.
+func (n *nutanixManager) getInstanceType(vm *vmmModels.Vm) string {
+	instanceTypes := n.getConfig().InstanceTypes
+	if instanceTypes == nil {
+		return constants.InstanceType
+	}
+	shape, ok := getVMShape(vm)
+	if !ok {
+		klog.V(1).Infof("unable to determine shape of VM %s, using instance type %s", ptr.Deref(vm.ExtId, ""), constants.InstanceType) //nolint:typecheck
+		return constants.InstanceType
+	}
+	for _, class := range instanceTypes.Classes {
+		if class.VCPUs != 0 && class.VCPUs != shape.vcpus {
+			continue
+		}
+		if class.MemoryGiB != 0 && int64(class.MemoryGiB)*gibibyte != shape.memoryBytes {
+			continue
+		}
+		if class.GPUs != nil && *class.GPUs != shape.gpus {
+			continue
+		}
+		return class.Name
+	}
+	return shape.String()
+}
+
+func getVMShape(vm *vmmModels.Vm) (vmShape, bool) {
+	if vm == nil || vm.NumSockets == nil || vm.NumCoresPerSocket == nil || vm.MemorySizeBytes == nil {
+		return vmShape{}, false
+	}
+	vcpus := *vm.NumSockets * *vm.NumCoresPerSocket * max(ptr.Deref(vm.NumThreadsPerCore, 1), 1)
+	if vcpus <= 0 || *vm.MemorySizeBytes <= 0 {
+		return vmShape{}, false
+	}
+	return vmShape{
+		vcpus:       vcpus,
+		memoryBytes: *vm.MemorySizeBytes,
+		gpus:        len(vm.Gpus),
+	}, true
+}
+
+// String names the shape like ahv-8c-32g or ahv-4c-1536m-1gpu. Memory is given in MiB if it is not a
+// multiple of a GiB.
+func (s vmShape) String() string {
+	memory := fmt.Sprintf("%dg", s.memoryBytes/gibibyte)
+	if s.memoryBytes%gibibyte != 0 {
+		memory = fmt.Sprintf("%dm", s.memoryBytes/mebibyte)
+	}
+	name := fmt.Sprintf("ahv-%dc-%s", s.vcpus, memory)
+	if s.gpus > 0 {
+		name = fmt.Sprintf("%s-%dgpu", name, s.gpus)
+	}
+	return name
+}
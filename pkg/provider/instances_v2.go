@@ -19,6 +19,7 @@ package provider
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/trace"
 	v1 "k8s.io/api/core/v1"
 	cloudprovider "k8s.io/cloud-provider"
 	"k8s.io/klog/v2"
@@ -35,7 +36,9 @@ func newInstancesV2(nutanixManager *nutanixManager) cloudprovider.InstancesV2 {
 }
 
 func (i *instancesV2) InstanceExists(ctx context.Context, node *v1.Node) (bool, error) {
+	ctx, span := i.nutanixManager.startSpan(ctx, "InstanceExists", node)
 	ok, err := i.nutanixManager.nodeExists(ctx, node)
+	finishInstanceOperation(span, "InstanceExists", outcome(ok, "exists", "not_found"), err)
 	if err != nil {
 		return ok, err
 	}
@@ -44,7 +47,9 @@ func (i *instancesV2) InstanceExists(ctx context.Context, node *v1.Node) (bool,
 }
 
 func (i *instancesV2) InstanceShutdown(ctx context.Context, node *v1.Node) (bool, error) {
+	ctx, span := i.nutanixManager.startSpan(ctx, "InstanceShutdown", node)
 	ok, err := i.nutanixManager.isNodeShutdown(ctx, node)
+	finishInstanceOperation(span, "InstanceShutdown", outcome(ok, "shutdown", "running"), err)
 	if err != nil {
 		return ok, err
 	}
@@ -53,10 +58,21 @@ func (i *instancesV2) InstanceShutdown(ctx context.Context, node *v1.Node) (bool
 }
 
 func (i *instancesV2) InstanceMetadata(ctx context.Context, node *v1.Node) (*cloudprovider.InstanceMetadata, error) {
+	ctx, span := i.nutanixManager.startSpan(ctx, "InstanceMetadata", node)
 	md, err := i.nutanixManager.getInstanceMetadata(ctx, node)
+	finishInstanceOperation(span, "InstanceMetadata", "success", err)
 	if err != nil {
 		return md, err
 	}
 	klog.V(1).InfoS("InstanceMetadata", "node", node.Name, "metadata", md) //nolint:typecheck
 	return md, err
 }
+
+// finishInstanceOperation records the result of an InstancesV2 operation and ends its span.
+func finishInstanceOperation(span trace.Span, operation string, result string, err error) {
+	if err != nil {
+		result = "error"
+	}
+	recordInstanceOperation(operation, result, err)
+	endSpan(span, result, err)
+}
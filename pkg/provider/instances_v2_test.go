@@ -19,16 +19,21 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go4.org/netipx"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
 
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
@@ -191,6 +196,26 @@ var _ = Describe("Test InstancesV2", func() { // nolint:typecheck
 			mock.ValidateInstanceMetadata(metadata, vm, "", "")
 		})
 
+		It("[TopologyDiscovery: Categories] should have zone and region set if TopologyCategories is passed in config and host has categories", func() {
+			// VM does not have categories but its host has
+			mockEnvironment.AssociateHostCategories(mock.MockHostUUID, mock.MockCategoryRegionUUID, mock.MockCategoryZoneUUID)
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			mock.ValidateInstanceMetadata(metadata, vm, mock.MockRegion, mock.MockZone)
+		})
+
+		It("[TopologyDiscovery: Categories] should combine categories of the VM and its host", func() {
+			mockEnvironment.AssociateHostCategories(mock.MockHostUUID, mock.MockCategoryZoneUUID)
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			vm.Categories = []vmmModels.CategoryReference{{ExtId: ptr.To(mock.MockCategoryRegionUUID)}}
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			mock.ValidateInstanceMetadata(metadata, vm, mock.MockRegion, mock.MockZone)
+		})
+
 		It("[TopologyDiscovery: Prism] should have PC name set as region and PE as zone", func() {
 			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
@@ -221,6 +246,52 @@ var _ = Describe("Test InstancesV2", func() { // nolint:typecheck
 			Expect(err).Should(HaveOccurred())
 		})
 
+		It("[TopologyDiscovery: Prism] should look up the VM in all Prism Centrals", func() {
+			otherEnvironment, err := mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+			Expect(err).ShouldNot(HaveOccurred())
+			otherEnvironment.DeleteCluster(*otherEnvironment.GetCluster(ctx, mock.MockPrismCentral).ExtId)
+			otherEnvironment.AddCluster(additionalPC)
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			mockEnvironment.DeleteVM(*vm.ExtId)
+			nutanixClient := mock.CreateMockClient(*mockEnvironment)
+			nutanixClient.AddPrismCentral("pc-2", *otherEnvironment)
+			i.nutanixManager.nutanixClient = nutanixClient
+			i.nutanixManager.config = prismTopologyConfig
+
+			e, err := i.InstanceExists(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(e).To(BeTrue())
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			mock.ValidateInstanceMetadata(metadata, vm, *additionalPC.Name, mock.MockCluster)
+
+			otherEnvironment.DeleteVM(*vm.ExtId)
+			e, err = i.InstanceExists(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(e).To(BeFalse())
+		})
+
+		It("[TopologyDiscovery: Prism] should look up the VM in the Prism Central of its provider ID", func() {
+			otherEnvironment, err := mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+			Expect(err).ShouldNot(HaveOccurred())
+			otherEnvironment.DeleteCluster(*otherEnvironment.GetCluster(ctx, mock.MockPrismCentral).ExtId)
+			otherEnvironment.AddCluster(additionalPC)
+			nutanixClient := mock.CreateMockClient(*mockEnvironment)
+			nutanixClient.AddPrismCentral("pc-2", *otherEnvironment)
+			i.nutanixManager.nutanixClient = nutanixClient
+			i.nutanixManager.config = prismTopologyConfig
+			i.nutanixManager.config.ProviderIDFormat = config.QualifiedProviderIDFormat
+
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			node.Spec.ProviderID = fmt.Sprintf("nutanix://%s/%s/%s", *additionalPC.ExtId, mock.MockClusterUUID, *vm.ExtId)
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(metadata.ProviderID).To(Equal(node.Spec.ProviderID))
+			Expect(metadata.Region).To(Equal(*additionalPC.Name))
+		})
+
 		It("should have all custom labels set if custom labels are enabled and VM is poweredOn", func() {
 			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
@@ -258,6 +329,198 @@ var _ = Describe("Test InstancesV2", func() { // nolint:typecheck
 			Expect(err).ToNot(HaveOccurred())
 			Expect(updatedNode.Labels).To(BeEmpty())
 		})
+
+		It("should propagate selected categories of the VM and its cluster to node labels", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOnClusterCategories)
+			i.nutanixManager.config.EnableCustomLabeling = false
+			i.nutanixManager.config.CategoryLabels = &config.CategoryLabels{
+				KeyPrefixes: []string{"K8s-"},
+				Keys:        []string{mock.MockDefaultZone},
+			}
+			_, err = i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(Equal(map[string]string{
+				constants.DefaultCategoryLabelDomain + "/" + mock.MockCategoryTeamKey: "Data-Science-ML",
+				constants.DefaultCategoryLabelDomain + "/" + mock.MockCategoryEnvKey:  mock.MockCategoryEnvValue,
+				constants.DefaultCategoryLabelDomain + "/" + mock.MockDefaultZone:     mock.MockZone,
+			}))
+		})
+
+		It("should prefer categories of the VM over its host and of the host over its cluster", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOnClusterCategories)
+			i.nutanixManager.config.EnableCustomLabeling = false
+			i.nutanixManager.config.CategoryLabels = &config.CategoryLabels{Keys: []string{mock.MockCategoryTeamKey}}
+			teamLabel := constants.DefaultCategoryLabelDomain + "/" + mock.MockCategoryTeamKey
+			// The team category of the host overrides the one of the cluster
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOnClusterCategories)
+			vm.Categories = vm.Categories[:2]
+			mockEnvironment.AssociateHostCategories(mock.MockHostUUID, mock.MockCategoryTeamUUID)
+			_, err = i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(HaveKeyWithValue(teamLabel, "Data-Science-ML"))
+
+			// The team category of the VM overrides the one of the host
+			vm.Categories = append(vm.Categories, vmmModels.CategoryReference{ExtId: ptr.To(mock.MockCategoryClusterTeamUUID)})
+			_, err = i.InstanceMetadata(ctx, updatedNode)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err = kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(HaveKeyWithValue(teamLabel, mock.MockCategoryClusterTeamValue))
+		})
+
+		It("should propagate categories to node labels under the configured domain", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOnClusterCategories)
+			i.nutanixManager.config.CategoryLabels = &config.CategoryLabels{
+				Domain: "example.com",
+				Keys:   []string{mock.MockCategoryEnvKey},
+			}
+			_, err = i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(HaveKeyWithValue("example.com/"+mock.MockCategoryEnvKey, mock.MockCategoryEnvValue))
+			Expect(updatedNode.Labels).To(HaveKey(constants.CustomPEUUIDLabel))
+		})
+
+		It("should update and remove stale category labels", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOnClusterCategories)
+			i.nutanixManager.config.CategoryLabels = &config.CategoryLabels{KeyPrefixes: []string{"K8s-"}}
+			teamLabel := constants.DefaultCategoryLabelDomain + "/" + mock.MockCategoryTeamKey
+			envLabel := constants.DefaultCategoryLabelDomain + "/" + mock.MockCategoryEnvKey
+			_, err = i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(HaveKeyWithValue(teamLabel, "Data-Science-ML"))
+			Expect(updatedNode.Labels).To(HaveKeyWithValue(envLabel, mock.MockCategoryEnvValue))
+			Expect(updatedNode.Annotations).To(HaveKey(constants.ManagedLabelsAnnotation))
+
+			// Labels set by other components are kept
+			updatedNode.Labels["example.com/unmanaged"] = "true"
+			_, err = kClient.CoreV1().Nodes().Update(ctx, updatedNode, metav1.UpdateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			// The team category of the cluster applies once the VM category is removed
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOnClusterCategories)
+			vm.Categories = vm.Categories[:2]
+			i.nutanixManager.config.CategoryLabels.KeyPrefixes = []string{mock.MockCategoryTeamKey}
+			_, err = i.InstanceMetadata(ctx, updatedNode)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err = kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(HaveKeyWithValue(teamLabel, mock.MockCategoryClusterTeamValue))
+			Expect(updatedNode.Labels).ToNot(HaveKey(envLabel))
+			Expect(updatedNode.Labels).To(HaveKeyWithValue("example.com/unmanaged", "true"))
+			Expect(updatedNode.Labels).To(HaveKey(constants.CustomHostNameLabel))
+		})
+
+		It("should remove all managed labels if labeling is disabled", func() {
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOnClusterCategories)
+			i.nutanixManager.config.CategoryLabels = &config.CategoryLabels{KeyPrefixes: []string{"K8s-"}}
+			_, err = i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err := kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).ToNot(BeEmpty())
+
+			i.nutanixManager.config.EnableCustomLabeling = false
+			i.nutanixManager.config.CategoryLabels = nil
+			_, err = i.InstanceMetadata(ctx, updatedNode)
+			Expect(err).ShouldNot(HaveOccurred())
+			updatedNode, err = kClient.CoreV1().Nodes().Get(ctx, node.ObjectMeta.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updatedNode.Labels).To(BeEmpty())
+			Expect(updatedNode.Annotations).ToNot(HaveKey(constants.ManagedLabelsAnnotation))
+		})
+	})
+
+	Context("Test VM lookup fallbacks", func() {
+		var node *v1.Node
+
+		BeforeEach(func() {
+			node = &v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: rand.String(10),
+				},
+				Status: v1.NodeStatus{
+					NodeInfo: v1.NodeSystemInfo{
+						SystemUUID: string(uuid.NewUUID()),
+					},
+				},
+			}
+			i.nutanixManager.config = prismTopologyConfig
+			i.nutanixManager.config.EnableCustomLabeling = false
+		})
+
+		It("should not look up the VM without fallbacks", func() {
+			node.Name = mock.MockVMNamePoweredOn
+			e, err := i.InstanceExists(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(e).To(BeFalse())
+		})
+
+		It("should find the VM by the node name", func() {
+			i.nutanixManager.config.VMLookup = &config.VMLookup{
+				Fallbacks: []config.VMLookupStrategy{config.NameVMLookupStrategy},
+			}
+			node.Name = mock.MockVMNamePoweredOn
+			node.Status.NodeInfo.SystemUUID = ""
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			mock.ValidateInstanceMetadata(metadata, mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn), mock.MockPrismCentral, mock.MockCluster)
+		})
+
+		It("should find the VM by an InternalIP of the node", func() {
+			i.nutanixManager.config.VMLookup = &config.VMLookup{
+				Fallbacks: []config.VMLookupStrategy{config.NameVMLookupStrategy, config.InternalIPVMLookupStrategy},
+			}
+			node.Status.Addresses = []v1.NodeAddress{
+				{Type: v1.NodeExternalIP, Address: mock.MockIP},
+				{Type: v1.NodeInternalIP, Address: "10.200.20.1"},
+			}
+			metadata, err := i.InstanceMetadata(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			mock.ValidateInstanceMetadata(metadata, mockEnvironment.GetVM(ctx, mock.MockVMNameFilteredNodeAddresses), mock.MockPrismCentral, mock.MockCluster)
+		})
+
+		It("should find the VM by the UUID annotation of the node", func() {
+			i.nutanixManager.config.VMLookup = &config.VMLookup{
+				Fallbacks: []config.VMLookupStrategy{config.AnnotationVMLookupStrategy},
+			}
+			node.Annotations = map[string]string{constants.DefaultVMUUIDAnnotation: mock.MockVMPoweredOffUUID}
+			shutdown, err := i.InstanceShutdown(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(shutdown).To(BeTrue())
+
+			i.nutanixManager.config.VMLookup.Annotation = "example.com/vm"
+			e, err := i.InstanceExists(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(e).To(BeFalse())
+		})
+
+		It("should fail if more than one VM matches", func() {
+			i.nutanixManager.config.VMLookup = &config.VMLookup{
+				Fallbacks: []config.VMLookupStrategy{config.InternalIPVMLookupStrategy},
+			}
+			node.Status.Addresses = []v1.NodeAddress{{Type: v1.NodeInternalIP, Address: mock.MockIP}}
+			_, err := i.InstanceExists(ctx, node)
+			Expect(err).To(MatchError(ContainSubstring(mock.MockVMPoweredOnUUID)))
+		})
+
+		It("should not look up the VM of a node with a provider ID", func() {
+			i.nutanixManager.config.VMLookup = &config.VMLookup{
+				Fallbacks: []config.VMLookupStrategy{config.NameVMLookupStrategy},
+			}
+			node.Name = mock.MockVMNamePoweredOn
+			node.Spec.ProviderID = "nutanix://" + node.Status.NodeInfo.SystemUUID
+			e, err := i.InstanceExists(ctx, node)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(e).To(BeFalse())
+		})
 	})
 
 	Context("Test NewInstancesV2", func() {
@@ -0,0 +1,35 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package interfaces
+
+import "errors"
+
+// Errors returned by Prism implementations, wrapping the error of the underlying API call.
+// Callers should check for them with errors.Is.
+var (
+	// ErrNotFound is returned if the requested entity does not exist
+	ErrNotFound = errors.New("entity not found")
+	// ErrUnauthorized is returned if the credentials are invalid or lack permissions
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrThrottled is returned if Prism Central rate limited the request
+	ErrThrottled = errors.New("request throttled")
+	// ErrUnavailable is returned if Prism Central could not be reached or failed to serve the request
+	ErrUnavailable = errors.New("prism central unavailable")
+	// ErrCircuitOpen is returned without calling Prism Central while requests are suspended after
+	// Prism Central was repeatedly unavailable
+	ErrCircuitOpen = errors.New("prism central requests suspended")
+)
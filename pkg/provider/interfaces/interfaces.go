@@ -20,20 +20,39 @@ import (
 	"context"
 
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 	"k8s.io/client-go/informers"
 )
 
 type Client interface {
+	// Get returns the client of the first Prism Central
 	Get() (Prism, error)
+	// GetForPrismCentral returns the client of the named Prism Central
+	GetForPrismCentral(name string) (Prism, error)
+	// PrismCentrals returns the names of the Prism Centrals in the order they are configured. The
+	// name of a single Prism Central is empty.
+	PrismCentrals() []string
 	SetInformers(sharedInformers informers.SharedInformerFactory)
 }
 
 type Prism interface {
 	GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error)
+	ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error)
 	GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error)
 	ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error)
 	GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error)
+	// ListCategories returns the categories matching the filter with their detailed associations
+	ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error)
 	GetClusterHost(ctx context.Context, clusterUuid string, hostUUID string) (*clusterModels.Host, error)
+	GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error)
+	ListLoadBalancerSessions(ctx context.Context, filter string) ([]networkingModels.LoadBalancerSession, error)
+	CreateLoadBalancerSession(ctx context.Context, session *networkingModels.LoadBalancerSession) error
+	UpdateLoadBalancerSession(ctx context.Context, sessionUUID string, session *networkingModels.LoadBalancerSession) error
+	DeleteLoadBalancerSession(ctx context.Context, sessionUUID string) error
+	ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error)
+	ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error)
+	CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error
+	DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error
 }
@@ -0,0 +1,435 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+
+	"go4.org/netipx"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// vipAllocation is a single entry of the load balancer IPAM ledger.
+// The ledger is a ConfigMap in the CCM namespace keyed by Service UID.
+type vipAllocation struct {
+	IP      string `json:"ip"`
+	Pool    string `json:"pool"`
+	Service string `json:"service"`
+}
+
+type ipPool struct {
+	name  string
+	ipSet *netipx.IPSet
+	// prefixes are the CIDR entries of the pool
+	prefixes []netip.Prefix
+}
+
+// contains returns true if the address can be allocated from the pool. The network and broadcast
+// addresses of IPv4 prefixes are excluded, except for /31 and /32 prefixes which have none.
+func (p *ipPool) contains(ip netip.Addr) bool {
+	if !p.ipSet.Contains(ip) {
+		return false
+	}
+	for _, prefix := range p.prefixes {
+		if prefix.Addr().Is4() && prefix.Bits() < 31 && (ip == prefix.Addr() || ip == netipx.PrefixLastIP(prefix)) {
+			return false
+		}
+	}
+	return true
+}
+
+// vipAllocator hands out virtual IPs for Services of type LoadBalancer.
+// Allocations are committed to the ledger ConfigMap using optimistic concurrency,
+// so that CCM restarts and leader changes never assign the same address twice.
+type vipAllocator struct {
+	client clientset.Interface
+	pools  []ipPool
+}
+
+func newVIPAllocator(lbConfig *config.LoadBalancer) (*vipAllocator, error) {
+	if lbConfig == nil {
+		return nil, fmt.Errorf("load balancer config cannot be nil when creating VIP allocator")
+	}
+
+	pools := make([]ipPool, 0, len(lbConfig.IPPools))
+	for _, pool := range lbConfig.IPPools {
+		ipSet, err := parseIPSet(fmt.Sprintf("loadBalancer IP pool %s", pool.Name), pool.Addresses)
+		if err != nil {
+			return nil, err
+		}
+		var prefixes []netip.Prefix
+		for _, entry := range pool.Addresses {
+			if prefix, err := netip.ParsePrefix(entry); err == nil {
+				prefixes = append(prefixes, prefix.Masked())
+			}
+		}
+		pools = append(pools, ipPool{
+			name:     pool.Name,
+			ipSet:    ipSet,
+			prefixes: prefixes,
+		})
+	}
+	return &vipAllocator{
+		pools: pools,
+	}, nil
+}
+
+// get returns the allocation recorded for the service or nil if there is none.
+func (a *vipAllocator) get(ctx context.Context, service *v1.Service) (*vipAllocation, error) {
+	if err := a.validateService(service); err != nil {
+		return nil, err
+	}
+	ns, err := GetCCMNamespace()
+	if err != nil {
+		return nil, err
+	}
+	cm, err := a.client.CoreV1().ConfigMaps(ns).Get(ctx, constants.LoadBalancerIPAMConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	ledger, err := decodeVIPLedger(cm)
+	if err != nil {
+		return nil, err
+	}
+	if allocation, ok := ledger[string(service.UID)]; ok {
+		return &allocation, nil
+	}
+	return nil, nil
+}
+
+// allocate returns the existing allocation of the service or reserves a new address for it. An
+// existing allocation which does not match the requested IP, pool or IP families of the service is
+// replaced.
+func (a *vipAllocator) allocate(ctx context.Context, service *v1.Service) (*vipAllocation, error) {
+	if err := a.validateService(service); err != nil {
+		return nil, err
+	}
+	ns, err := GetCCMNamespace()
+	if err != nil {
+		return nil, err
+	}
+
+	var allocation *vipAllocation
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := a.getOrCreateLedger(ctx, ns)
+		if err != nil {
+			return err
+		}
+		ledger, err := decodeVIPLedger(cm)
+		if err != nil {
+			return err
+		}
+		if existing, ok := ledger[string(service.UID)]; ok {
+			if allocationMatchesService(existing, service) {
+				allocation = &existing
+				return nil
+			}
+			klog.Infof("load balancer IP %s from pool %s of service %s does not match the requested IP, pool or IP families, reallocating it", existing.IP, existing.Pool, existing.Service) //nolint:typecheck
+			delete(ledger, string(service.UID))
+		}
+
+		newAllocation, err := a.pickAddress(service, ledger)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(newAllocation)
+		if err != nil {
+			return err
+		}
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[string(service.UID)] = string(data)
+		if _, err = a.client.CoreV1().ConfigMaps(ns).Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		klog.Infof("allocated load balancer IP %s from pool %s to service %s", newAllocation.IP, newAllocation.Pool, newAllocation.Service) //nolint:typecheck
+		allocation = newAllocation
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate load balancer IP for service %s: %w", serviceKey(service), err)
+	}
+
+	if err := a.annotateService(ctx, service, allocation); err != nil {
+		return nil, err
+	}
+	return allocation, nil
+}
+
+// release removes the allocation of the service from the ledger and its annotations.
+func (a *vipAllocator) release(ctx context.Context, service *v1.Service) error {
+	if err := a.validateService(service); err != nil {
+		return err
+	}
+	ns, err := GetCCMNamespace()
+	if err != nil {
+		return err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, err := a.client.CoreV1().ConfigMaps(ns).Get(ctx, constants.LoadBalancerIPAMConfigMapName, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if _, ok := cm.Data[string(service.UID)]; !ok {
+			return nil
+		}
+		delete(cm.Data, string(service.UID))
+		_, err = a.client.CoreV1().ConfigMaps(ns).Update(ctx, cm, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release load balancer IP of service %s: %w", serviceKey(service), err)
+	}
+	klog.Infof("released load balancer IP of service %s", serviceKey(service)) //nolint:typecheck
+
+	return a.annotateService(ctx, service, nil)
+}
+
+func (a *vipAllocator) validateService(service *v1.Service) error {
+	if a.client == nil {
+		return fmt.Errorf("kubernetes client not initialized for VIP allocator")
+	}
+	if service == nil {
+		return fmt.Errorf("service cannot be nil when allocating load balancer IPs")
+	}
+	if service.UID == "" {
+		return fmt.Errorf("service %s has no UID", serviceKey(service))
+	}
+	return nil
+}
+
+func (a *vipAllocator) getOrCreateLedger(ctx context.Context, namespace string) (*v1.ConfigMap, error) {
+	cm, err := a.client.CoreV1().ConfigMaps(namespace).Get(ctx, constants.LoadBalancerIPAMConfigMapName, metav1.GetOptions{})
+	if err == nil {
+		return cm, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	cm = &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.LoadBalancerIPAMConfigMapName,
+			Namespace: namespace,
+		},
+		Data: map[string]string{},
+	}
+	cm, err = a.client.CoreV1().ConfigMaps(namespace).Create(ctx, cm, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// Another instance created the ledger concurrently, retry with its content
+		return nil, apierrors.NewConflict(v1.Resource("configmaps"), constants.LoadBalancerIPAMConfigMapName, err)
+	}
+	return cm, err
+}
+
+// pickAddress selects the address for a service that has no ledger entry yet.
+// An address requested through spec.loadBalancerIP or left behind in the service annotation
+// is honoured if it is free, otherwise the first free address of the eligible pools is used.
+// Only addresses of the IP families of the service are used, preferring its primary family.
+func (a *vipAllocator) pickAddress(service *v1.Service, ledger map[string]vipAllocation) (*vipAllocation, error) {
+	used := make(map[netip.Addr]string, len(ledger))
+	for _, allocation := range ledger {
+		ip, err := netip.ParseAddr(allocation.IP)
+		if err != nil {
+			klog.Warningf("ignoring invalid IP %q in load balancer IPAM ledger", allocation.IP) //nolint:typecheck
+			continue
+		}
+		used[ip] = allocation.Service
+	}
+
+	pools := a.pools
+	if poolName := service.Annotations[constants.LoadBalancerIPPoolAnnotation]; poolName != "" {
+		pools = nil
+		for _, pool := range a.pools {
+			if pool.name == poolName {
+				pools = []ipPool{pool}
+				break
+			}
+		}
+		if pools == nil {
+			return nil, fmt.Errorf("load balancer IP pool %s requested by service %s does not exist", poolName, serviceKey(service))
+		}
+	}
+
+	families := serviceIPFamilies(service)
+
+	if requestedIP := service.Spec.LoadBalancerIP; requestedIP != "" {
+		ip, err := netip.ParseAddr(requestedIP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse requested load balancer IP %q: %v", requestedIP, err)
+		}
+		if !hasIPFamily(families, ip) {
+			return nil, fmt.Errorf("requested load balancer IP %s is not of the IP families %v of service %s", requestedIP, families, serviceKey(service))
+		}
+		if owner, ok := used[ip]; ok {
+			return nil, fmt.Errorf("requested load balancer IP %s is already allocated to service %s", requestedIP, owner)
+		}
+		if pool := findPoolForIP(pools, ip); pool != nil {
+			return &vipAllocation{IP: ip.String(), Pool: pool.name, Service: serviceKey(service)}, nil
+		}
+		return nil, fmt.Errorf("requested load balancer IP %s is not an allocatable address of any eligible IP pool", requestedIP)
+	}
+
+	// Prefer the previously recorded address, e.g. when the ledger was lost
+	if previousIP := service.Annotations[constants.LoadBalancerIPAnnotation]; previousIP != "" {
+		if ip, err := netip.ParseAddr(previousIP); err == nil && hasIPFamily(families, ip) {
+			if _, ok := used[ip]; !ok {
+				if pool := findPoolForIP(pools, ip); pool != nil {
+					return &vipAllocation{IP: ip.String(), Pool: pool.name, Service: serviceKey(service)}, nil
+				}
+			}
+		}
+		klog.Warningf("previously allocated load balancer IP %s of service %s is not available anymore", previousIP, serviceKey(service)) //nolint:typecheck
+	}
+
+	candidateFamilies := families
+	if len(candidateFamilies) == 0 {
+		candidateFamilies = []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}
+	}
+	for _, family := range candidateFamilies {
+		for _, pool := range pools {
+			for _, ipRange := range pool.ipSet.Ranges() {
+				// Ranges of an IPSet never mix address families
+				if ipFamilyOf(ipRange.From()) != config.IPFamily(family) {
+					continue
+				}
+				for ip := ipRange.From(); ip.IsValid() && ip.Compare(ipRange.To()) <= 0; ip = ip.Next() {
+					if _, ok := used[ip]; !ok && pool.contains(ip) {
+						return &vipAllocation{IP: ip.String(), Pool: pool.name, Service: serviceKey(service)}, nil
+					}
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("no free %v addresses left in the eligible load balancer IP pools", candidateFamilies)
+}
+
+// serviceIPFamilies returns the IP families a load balancer IP of the service may have, in order of
+// preference, or nil if the API server did not set the families of the service.
+func serviceIPFamilies(service *v1.Service) []v1.IPFamily {
+	families := service.Spec.IPFamilies
+	if service.Spec.IPFamilyPolicy != nil && *service.Spec.IPFamilyPolicy == v1.IPFamilyPolicySingleStack && len(families) > 1 {
+		families = families[:1]
+	}
+	return families
+}
+
+// hasIPFamily returns true if the address is of one of the families, or if no family is given.
+func hasIPFamily(families []v1.IPFamily, ip netip.Addr) bool {
+	if len(families) == 0 {
+		return true
+	}
+	for _, family := range families {
+		if ipFamilyOf(ip) == config.IPFamily(family) {
+			return true
+		}
+	}
+	return false
+}
+
+// allocationMatchesService returns false if the service requests another IP, pool or IP family than
+// allocated.
+func allocationMatchesService(allocation vipAllocation, service *v1.Service) bool {
+	if ip, err := netip.ParseAddr(allocation.IP); err != nil || !hasIPFamily(serviceIPFamilies(service), ip) {
+		return false
+	}
+	if requestedIP := service.Spec.LoadBalancerIP; requestedIP != "" {
+		ip, err := netip.ParseAddr(requestedIP)
+		if err != nil || ip.String() != allocation.IP {
+			return false
+		}
+	}
+	poolName := service.Annotations[constants.LoadBalancerIPPoolAnnotation]
+	return poolName == "" || poolName == allocation.Pool
+}
+
+// annotateService records the allocated IP on the service, or removes the IP annotation if allocation is nil.
+// The annotation allows an allocation to be restored if the ledger is lost.
+// The pool annotation is never written, as it is read as the pool requested by the user.
+func (a *vipAllocator) annotateService(ctx context.Context, service *v1.Service, allocation *vipAllocation) error {
+	annotations := map[string]interface{}{}
+	switch {
+	case allocation == nil:
+		if _, ok := service.Annotations[constants.LoadBalancerIPAnnotation]; !ok {
+			return nil
+		}
+		annotations[constants.LoadBalancerIPAnnotation] = nil
+	case service.Annotations[constants.LoadBalancerIPAnnotation] == allocation.IP:
+		return nil
+	default:
+		annotations[constants.LoadBalancerIPAnnotation] = allocation.IP
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = a.client.CoreV1().Services(service.Namespace).Patch(ctx, service.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to update load balancer annotations on service %s: %w", serviceKey(service), err)
+	}
+	return nil
+}
+
+func findPoolForIP(pools []ipPool, ip netip.Addr) *ipPool {
+	for i := range pools {
+		if pools[i].contains(ip) {
+			return &pools[i]
+		}
+	}
+	return nil
+}
+
+func decodeVIPLedger(cm *v1.ConfigMap) (map[string]vipAllocation, error) {
+	ledger := make(map[string]vipAllocation, len(cm.Data))
+	for uid, value := range cm.Data {
+		allocation := vipAllocation{}
+		if err := json.Unmarshal([]byte(value), &allocation); err != nil {
+			return nil, fmt.Errorf("failed to decode load balancer IPAM entry %s: %v", uid, err)
+		}
+		ledger[uid] = allocation
+	}
+	return ledger, nil
+}
+
+func serviceKey(service *v1.Service) string {
+	return fmt.Sprintf("%s/%s", service.Namespace, service.Name)
+}
@@ -18,43 +18,109 @@ package provider
 
 import (
 	"context"
+	"fmt"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
 )
 
-// get the status from the service Info.
-// returning an error - keeps the intent alive
-// bool indicates if an lb was found or not
-func (nc *NtnxCloud) GetLoadBalancer(ct context.Context, clusterName string, service *v1.Service) (
+const errLoadBalancerNotEnabled = "load balancer support is not enabled in the cloud config"
+
+// GetLoadBalancer returns the status of the load balancer of the service.
+// The bool indicates whether a virtual IP has been allocated to the service.
+func (nc *NtnxCloud) GetLoadBalancer(ctx context.Context, clusterName string, service *v1.Service) (
 	*v1.LoadBalancerStatus, bool, error,
 ) {
-	return nil, false, nil
+	if nc.flowVPCLoadBalancer != nil {
+		return nc.flowVPCLoadBalancer.get(ctx, clusterName, service)
+	}
+	if nc.vipAllocator == nil {
+		return nil, false, nil
+	}
+	allocation, err := nc.vipAllocator.get(ctx, service)
+	if err != nil {
+		return nil, false, err
+	}
+	if allocation == nil {
+		return nil, false, nil
+	}
+	return loadBalancerStatus(allocation), true, nil
 }
 
 // GetLoadBalancerName returns the name of the load balancer. Implementations must treat the
 // *v1.Service parameter as read-only and not modify it.
-func (nc *NtnxCloud) GetLoadBalancerName(ct context.Context, clusterName string, service *v1.Service) string {
-	return ""
+func (nc *NtnxCloud) GetLoadBalancerName(ctx context.Context, clusterName string, service *v1.Service) string {
+	return loadBalancerName(clusterName, service)
 }
 
-// It adds an entry "create" into the internal method call record.
-func (nc *NtnxCloud) EnsureLoadBalancer(ct context.Context,
+// EnsureLoadBalancer allocates a virtual IP for the service if it has none yet
+// and returns the resulting status. With Flow VPC load balancers, the sessions
+// of the service are reconciled as well.
+func (nc *NtnxCloud) EnsureLoadBalancer(ctx context.Context,
 	clusterName string, service *v1.Service, nodes []*v1.Node) (
 	*v1.LoadBalancerStatus, error,
 ) {
-	return nil, nil
+	if nc.flowVPCLoadBalancer != nil {
+		return nc.flowVPCLoadBalancer.ensure(ctx, clusterName, service, nodes)
+	}
+	if nc.vipAllocator == nil {
+		return nil, fmt.Errorf(errLoadBalancerNotEnabled)
+	}
+	allocation, err := nc.vipAllocator.allocate(ctx, service)
+	if err != nil {
+		return nil, err
+	}
+	klog.V(1).InfoS("EnsureLoadBalancer", "service", serviceKey(service), "ip", allocation.IP, "pool", allocation.Pool) //nolint:typecheck
+	return loadBalancerStatus(allocation), nil
 }
 
-func (nc *NtnxCloud) UpdateLoadBalancer(ct context.Context,
+// UpdateLoadBalancer makes sure the service keeps its virtual IP. The allocation does not
+// depend on the set of nodes, so there is nothing else to update unless Flow VPC load
+// balancers are used, whose targets follow the nodes.
+func (nc *NtnxCloud) UpdateLoadBalancer(ctx context.Context,
 	clusterName string, service *v1.Service, nodes []*v1.Node,
 ) error {
-	return nil
+	if nc.flowVPCLoadBalancer != nil {
+		_, err := nc.flowVPCLoadBalancer.ensure(ctx, clusterName, service, nodes)
+		return err
+	}
+	if nc.vipAllocator == nil {
+		return fmt.Errorf(errLoadBalancerNotEnabled)
+	}
+	_, err := nc.vipAllocator.allocate(ctx, service)
+	return err
 }
 
-// EnsureLoadBalancerDeleted is a test-spy implementation of LoadBalancer.EnsureLoadBalancerDeleted.
-// It adds an entry "delete" into the internal method call record.
-func (nc *NtnxCloud) EnsureLoadBalancerDeleted(ct context.Context, clusterName string,
+// EnsureLoadBalancerDeleted releases the virtual IP of the service and deletes its Flow VPC
+// load balancer sessions. It succeeds if no virtual IP was allocated.
+func (nc *NtnxCloud) EnsureLoadBalancerDeleted(ctx context.Context, clusterName string,
 	service *v1.Service,
 ) error {
-	return nil
+	if nc.flowVPCLoadBalancer != nil {
+		return nc.flowVPCLoadBalancer.delete(ctx, clusterName, service)
+	}
+	if nc.vipAllocator == nil {
+		return nil
+	}
+	return nc.vipAllocator.release(ctx, service)
+}
+
+func loadBalancerStatus(allocation *vipAllocation) *v1.LoadBalancerStatus {
+	return &v1.LoadBalancerStatus{
+		Ingress: []v1.LoadBalancerIngress{
+			{
+				IP: allocation.IP,
+			},
+		},
+	}
+}
+
+// loadBalancerName derives the load balancer name from the cluster name and the Service UID.
+// The UID makes the name unique, the cluster prefix allows finding the load balancers of a cluster.
+func loadBalancerName(clusterName string, service *v1.Service) string {
+	return loadBalancerNamePrefix(clusterName) + string(service.UID)
+}
+
+func loadBalancerNamePrefix(clusterName string) string {
+	return fmt.Sprintf("k8s-%s-", resourceClusterName(clusterName))
 }
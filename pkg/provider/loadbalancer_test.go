@@ -19,12 +19,24 @@ package provider
 
 import (
 	"context"
+	"os"
+	"strings"
 
+	"github.com/google/uuid"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
 
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
 
 var _ = Describe("Test Loadbalancer", func() { // nolint:typecheck
@@ -48,38 +60,577 @@ var _ = Describe("Test Loadbalancer", func() { // nolint:typecheck
 	})
 
 	Context("Test GetLoadBalancerName", func() {
-		It("should return empty outputs", func() {
-			n := ntnxCloud.GetLoadBalancerName(ctx, mock.MockCluster, &v1.Service{})
-			Expect(n).To(BeEmpty())
+		It("should return a name derived from the cluster name and service UID", func() {
+			service := &v1.Service{ObjectMeta: metav1.ObjectMeta{UID: "6e6a3cbd-39c9-4a4b-8e67-8b9b1d2c3e4f"}}
+			n := ntnxCloud.GetLoadBalancerName(ctx, mock.MockCluster, service)
+			Expect(n).To(Equal("k8s-mock-cluster-6e6a3cbd-39c9-4a4b-8e67-8b9b1d2c3e4f"))
+		})
+
+		It("should sanitize and truncate the cluster name", func() {
+			service := &v1.Service{ObjectMeta: metav1.ObjectMeta{UID: "6e6a3cbd-39c9-4a4b-8e67-8b9b1d2c3e4f"}}
+			Expect(ntnxCloud.GetLoadBalancerName(ctx, "My_Cluster.Prod", service)).To(Equal("k8s-my-cluster-prod-6e6a3cbd-39c9-4a4b-8e67-8b9b1d2c3e4f"))
+			Expect(ntnxCloud.GetLoadBalancerName(ctx, "", service)).To(Equal("k8s-kubernetes-6e6a3cbd-39c9-4a4b-8e67-8b9b1d2c3e4f"))
+			long := ntnxCloud.GetLoadBalancerName(ctx, strings.Repeat("a", 100), service)
+			Expect(long).To(Equal("k8s-" + strings.Repeat("a", 32) + "-6e6a3cbd-39c9-4a4b-8e67-8b9b1d2c3e4f"))
 		})
 	})
 
 	Context("Test EnsureLoadBalancer", func() {
-		It("should not return error", func() {
+		It("should return error if load balancer support is not enabled", func() {
 			lbStatus, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, &v1.Service{}, []*v1.Node{})
 			Expect(lbStatus).To(BeNil())
-			Expect(err).ShouldNot(HaveOccurred())
+			Expect(err).Should(HaveOccurred())
 		})
 	})
 
 	Context("Test UpdateLoadBalancer", func() {
-		It("should not return error", func() {
+		It("should return error if load balancer support is not enabled", func() {
 			err := ntnxCloud.UpdateLoadBalancer(ctx, mock.MockCluster, &v1.Service{}, []*v1.Node{})
-			Expect(err).ShouldNot(HaveOccurred())
+			Expect(err).Should(HaveOccurred())
 		})
 	})
 
-	Context("Test UpdateLoadBalancer", func() {
+	Context("Test EnsureLoadNalancerDeleted", func() {
 		It("not return error", func() {
-			err := ntnxCloud.UpdateLoadBalancer(ctx, mock.MockCluster, &v1.Service{}, []*v1.Node{})
+			err := ntnxCloud.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, &v1.Service{})
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 	})
 
-	Context("Test EnsureLoadNalancerDeleted", func() {
-		It("not return error", func() {
-			err := ntnxCloud.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, &v1.Service{})
-			Expect(err).ShouldNot(HaveOccurred())
+	Context("Test load balancer with IP pools", func() {
+		var (
+			kClient  *fake.Clientset
+			lbConfig *config.LoadBalancer
+		)
+
+		newService := func(name string) *v1.Service {
+			service := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					UID:       types.UID(name + "-uid"),
+				},
+				Spec: v1.ServiceSpec{
+					Type: v1.ServiceTypeLoadBalancer,
+				},
+			}
+			s, err := kClient.CoreV1().Services(service.Namespace).Create(ctx, service, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			return s
+		}
+
+		newCloud := func() NtnxCloud {
+			allocator, err := newVIPAllocator(lbConfig)
+			Expect(err).ToNot(HaveOccurred())
+			allocator.client = kClient
+			return NtnxCloud{
+				config:       config.Config{LoadBalancer: lbConfig},
+				vipAllocator: allocator,
+			}
+		}
+
+		BeforeEach(func() {
+			os.Setenv(constants.CCMNamespaceKey, "kube-system")
+			kClient = fake.NewSimpleClientset()
+			lbConfig = &config.LoadBalancer{
+				IPPools: []config.IPPool{
+					{
+						Name:      "primary",
+						Addresses: []string{"10.0.0.10-10.0.0.11"},
+					},
+					{
+						Name:      "secondary",
+						Addresses: []string{"10.1.0.0/30"},
+					},
+				},
+			}
+			ntnxCloud = newCloud()
+		})
+
+		AfterEach(func() {
+			os.Unsetenv(constants.CCMNamespaceKey)
+		})
+
+		It("should report load balancer support", func() {
+			_, ok := ntnxCloud.LoadBalancer()
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should allocate addresses in pool order and record them", func() {
+			for _, expected := range []string{"10.0.0.10", "10.0.0.11", "10.1.0.1"} {
+				service := newService("svc-" + expected)
+				status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Ingress).To(ConsistOf(v1.LoadBalancerIngress{IP: expected}))
+
+				updated, err := kClient.CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(updated.Annotations).To(HaveKeyWithValue(constants.LoadBalancerIPAnnotation, expected))
+			}
+
+			cm, err := kClient.CoreV1().ConfigMaps("kube-system").Get(ctx, constants.LoadBalancerIPAMConfigMapName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cm.Data).To(HaveLen(3))
+		})
+
+		It("should be idempotent and survive a restart", func() {
+			service := newService("svc")
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			restarted := newCloud()
+			again, err := restarted.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(again).To(Equal(status))
+
+			current, found, err := restarted.GetLoadBalancer(ctx, mock.MockCluster, service)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(current).To(Equal(status))
+
+			other := newService("other")
+			otherStatus, err := restarted.EnsureLoadBalancer(ctx, mock.MockCluster, other, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(otherStatus.Ingress[0].IP).ToNot(Equal(status.Ingress[0].IP))
+		})
+
+		It("should honour the requested pool and IP", func() {
+			service := newService("pool")
+			service.Annotations = map[string]string{constants.LoadBalancerIPPoolAnnotation: "secondary"}
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.1.0.1"))
+
+			requested := newService("requested")
+			requested.Spec.LoadBalancerIP = "10.1.0.2"
+			status, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, requested, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.1.0.2"))
+
+			conflicting := newService("conflicting")
+			conflicting.Spec.LoadBalancerIP = "10.1.0.2"
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, conflicting, nil)
+			Expect(err).To(HaveOccurred())
+
+			broadcast := newService("broadcast")
+			broadcast.Spec.LoadBalancerIP = "10.1.0.3"
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, broadcast, nil)
+			Expect(err).To(HaveOccurred())
+
+			outside := newService("outside")
+			outside.Spec.LoadBalancerIP = "192.168.0.1"
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, outside, nil)
+			Expect(err).To(HaveOccurred())
+
+			unknownPool := newService("unknown-pool")
+			unknownPool.Annotations = map[string]string{constants.LoadBalancerIPPoolAnnotation: "unknown"}
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, unknownPool, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should reallocate the address if the requested IP or pool changes", func() {
+			service := newService("change")
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.0.0.10"))
+
+			service.Spec.LoadBalancerIP = "10.0.0.11"
+			status, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.0.0.11"))
+
+			service.Spec.LoadBalancerIP = ""
+			service.Annotations = map[string]string{constants.LoadBalancerIPPoolAnnotation: "secondary"}
+			status, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.1.0.1"))
+
+			// The address of other services cannot be taken over
+			other := newService("other")
+			otherStatus, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, other, nil)
+			Expect(err).ToNot(HaveOccurred())
+			service.Annotations = nil
+			service.Spec.LoadBalancerIP = otherStatus.Ingress[0].IP
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).To(MatchError(ContainSubstring("already allocated")))
+			current, found, err := ntnxCloud.GetLoadBalancer(ctx, mock.MockCluster, service)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(current.Ingress[0].IP).To(Equal("10.1.0.1"))
+
+			cm, err := kClient.CoreV1().ConfigMaps("kube-system").Get(ctx, constants.LoadBalancerIPAMConfigMapName, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cm.Data).To(HaveLen(2))
+		})
+
+		It("should restore the previous address from the service annotation", func() {
+			service := newService("restore")
+			service.Annotations = map[string]string{constants.LoadBalancerIPAnnotation: "10.1.0.2"}
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.1.0.2"))
+		})
+
+		It("should not record the chosen pool as a requested pool", func() {
+			service := newService("renamed")
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.0.0.10"))
+			service, err = kClient.CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(service.Annotations).ToNot(HaveKey(constants.LoadBalancerIPPoolAnnotation))
+
+			// The pool is renamed and the ledger is lost
+			lbConfig.IPPools[0].Name = "renamed"
+			ntnxCloud = newCloud()
+			Expect(kClient.CoreV1().ConfigMaps("kube-system").Delete(ctx, constants.LoadBalancerIPAMConfigMapName, metav1.DeleteOptions{})).To(Succeed())
+			status, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.0.0.10"))
+		})
+
+		It("should only allocate addresses of the IP families of the service", func() {
+			lbConfig.IPPools = []config.IPPool{
+				{Name: "ipv6", Addresses: []string{"fd00::10-fd00::11"}},
+				{Name: "dual", Addresses: []string{"fd00:1::10", "10.2.0.10"}},
+			}
+			ntnxCloud = newCloud()
+
+			ipv4 := newService("ipv4")
+			ipv4.Spec.IPFamilies = []v1.IPFamily{v1.IPv4Protocol}
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, ipv4, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.2.0.10"))
+
+			ipv6 := newService("ipv6")
+			ipv6.Spec.IPFamilies = []v1.IPFamily{v1.IPv6Protocol}
+			status, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, ipv6, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("fd00::10"))
+
+			dualStack := newService("dual-stack")
+			dualStack.Spec.IPFamilies = []v1.IPFamily{v1.IPv4Protocol, v1.IPv6Protocol}
+			dualStack.Spec.IPFamilyPolicy = ptr.To(v1.IPFamilyPolicyPreferDualStack)
+			status, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, dualStack, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("fd00::11"))
+
+			exhausted := newService("exhausted")
+			exhausted.Spec.IPFamilies = []v1.IPFamily{v1.IPv4Protocol}
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, exhausted, nil)
+			Expect(err).To(MatchError(ContainSubstring("no free [IPv4] addresses")))
+
+			requested := newService("requested")
+			requested.Spec.IPFamilies = []v1.IPFamily{v1.IPv4Protocol}
+			requested.Spec.LoadBalancerIP = "fd00:1::10"
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, requested, nil)
+			Expect(err).To(MatchError(ContainSubstring("is not of the IP families")))
+		})
+
+		It("should not allocate the network and broadcast addresses of IPv4 prefixes", func() {
+			lbConfig.IPPools = []config.IPPool{{Name: "prefix", Addresses: []string{"10.1.0.0/30", "10.2.0.0/31"}}}
+			ntnxCloud = newCloud()
+			for _, expected := range []string{"10.1.0.1", "10.1.0.2", "10.2.0.0", "10.2.0.1"} {
+				status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, newService("svc-"+expected), nil)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(status.Ingress[0].IP).To(Equal(expected))
+			}
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, newService("exhausted"), nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail when the pools are exhausted", func() {
+			lbConfig.IPPools = lbConfig.IPPools[:1]
+			ntnxCloud = newCloud()
+			for _, name := range []string{"a", "b"} {
+				_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, newService(name), nil)
+				Expect(err).ToNot(HaveOccurred())
+			}
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, newService("c"), nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should release the address on deletion", func() {
+			service := newService("release")
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nil)
+			Expect(err).ToNot(HaveOccurred())
+			service, err = kClient.CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ntnxCloud.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, service)).To(Succeed())
+			_, found, err := ntnxCloud.GetLoadBalancer(ctx, mock.MockCluster, service)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+
+			updated, err := kClient.CoreV1().Services(service.Namespace).Get(ctx, service.Name, metav1.GetOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(updated.Annotations).ToNot(HaveKey(constants.LoadBalancerIPAnnotation))
+
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, newService("next"), nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.0.0.10"))
+		})
+
+		It("should not fail to release if the service was never allocated", func() {
+			Expect(ntnxCloud.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, newService("never"))).To(Succeed())
+		})
+	})
+
+	Context("Test Flow VPC load balancer", func() {
+		var (
+			kClient         *fake.Clientset
+			mockEnvironment *mock.MockEnvironment
+			lbConfig        *config.LoadBalancer
+			nodes           []*v1.Node
+		)
+
+		newService := func(name string, ports ...v1.ServicePort) *v1.Service {
+			service := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					UID:       types.UID(uuid.NewString()),
+				},
+				Spec: v1.ServiceSpec{
+					Type:  v1.ServiceTypeLoadBalancer,
+					Ports: ports,
+				},
+			}
+			s, err := kClient.CoreV1().Services(service.Namespace).Create(ctx, service, metav1.CreateOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			return s
+		}
+
+		newCloud := func() NtnxCloud {
+			m, err := newNutanixManager(config.Config{LoadBalancer: lbConfig})
+			Expect(err).ToNot(HaveOccurred())
+			m.client = kClient
+			m.nutanixClient = mock.CreateMockClient(*mockEnvironment)
+			cloud := NtnxCloud{
+				config:  config.Config{LoadBalancer: lbConfig},
+				manager: m,
+			}
+			if len(lbConfig.IPPools) > 0 {
+				cloud.vipAllocator, err = newVIPAllocator(lbConfig)
+				Expect(err).ToNot(HaveOccurred())
+				cloud.vipAllocator.client = kClient
+			}
+			cloud.flowVPCLoadBalancer, err = newFlowVPCLoadBalancer(m, lbConfig, cloud.vipAllocator)
+			Expect(err).ToNot(HaveOccurred())
+			return cloud
+		}
+
+		sessionsByName := func() map[string]*networkingModels.LoadBalancerSession {
+			sessions := map[string]*networkingModels.LoadBalancerSession{}
+			for _, session := range mockEnvironment.GetLoadBalancerSessions() {
+				sessions[*session.Name] = session
+			}
+			return sessions
+		}
+
+		httpPort := v1.ServicePort{Name: "http", Protocol: v1.ProtocolTCP, Port: 80, NodePort: 30080}
+		dnsPort := v1.ServicePort{Name: "dns", Protocol: v1.ProtocolUDP, Port: 53, NodePort: 30053}
+
+		BeforeEach(func() {
+			var err error
+			os.Setenv(constants.CCMNamespaceKey, "kube-system")
+			kClient = fake.NewSimpleClientset()
+			mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+			Expect(err).ToNot(HaveOccurred())
+			lbConfig = &config.LoadBalancer{
+				Type: config.FlowVPCLoadBalancerType,
+				FlowVPC: &config.FlowVPCLoadBalancer{
+					VPCUUID:       "vpc-uuid",
+					VIPSubnetUUID: "vip-subnet-uuid",
+				},
+			}
+			nodes = []*v1.Node{
+				mockEnvironment.GetNode(mock.MockVMNamePoweredOn),
+				mockEnvironment.GetNode(mock.MockVMNameCategories),
+			}
+			ntnxCloud = newCloud()
+		})
+
+		AfterEach(func() {
+			os.Unsetenv(constants.CCMNamespaceKey)
+		})
+
+		It("should create a session per port sharing the assigned virtual IP", func() {
+			service := newService("web", httpPort, dnsPort)
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress).To(ConsistOf(v1.LoadBalancerIngress{IP: mock.MockLoadBalancerVIP}))
+
+			baseName := ntnxCloud.GetLoadBalancerName(ctx, mock.MockCluster, service)
+			sessions := sessionsByName()
+			Expect(sessions).To(HaveLen(2))
+			Expect(sessions).To(HaveKey(baseName + "-tcp-80"))
+			Expect(sessions).To(HaveKey(baseName + "-udp-53"))
+
+			http := sessions[baseName+"-tcp-80"]
+			Expect(*http.VpcReference).To(Equal("vpc-uuid"))
+			Expect(*http.Listener.Protocol).To(Equal(networkingModels.PROTOCOL_TCP))
+			Expect(*http.Listener.PortRanges[0].StartPort).To(Equal(80))
+			Expect(*http.Listener.VirtualIP.SubnetReference).To(Equal("vip-subnet-uuid"))
+			Expect(http.TargetsConfig.NicTargets).To(HaveLen(2))
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(http.TargetsConfig.NicTargets).To(ContainElement(networkingModels.NicTarget{
+				VmReference:         vm.ExtId,
+				VirtualNicReference: vm.Nics[0].ExtId,
+				Port:                ptr.To(30080),
+			}))
+
+			dns := sessions[baseName+"-udp-53"]
+			Expect(*dns.Listener.VirtualIP.AssignmentType).To(Equal(networkingModels.ASSIGNMENTTYPE_STATIC))
+			Expect(*dns.Listener.VirtualIP.IpAddress.Ipv4.Value).To(Equal(mock.MockLoadBalancerVIP))
+
+			current, found, err := ntnxCloud.GetLoadBalancer(ctx, mock.MockCluster, service)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(current).To(Equal(status))
+		})
+
+		It("should skip nodes whose VM is managed by another Prism Central", func() {
+			otherEnvironment, err := mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+			Expect(err).ToNot(HaveOccurred())
+			otherVM := mockEnvironment.GetVM(ctx, mock.MockVMNameCategories)
+			mockEnvironment.DeleteVM(*otherVM.ExtId)
+			nutanixClient := mock.CreateMockClient(*mockEnvironment)
+			nutanixClient.AddPrismCentral("pc-2", *otherEnvironment)
+			ntnxCloud.manager.nutanixClient = nutanixClient
+
+			service := newService("web", httpPort)
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).ToNot(HaveOccurred())
+			baseName := ntnxCloud.GetLoadBalancerName(ctx, mock.MockCluster, service)
+			targets := sessionsByName()[baseName+"-tcp-80"].TargetsConfig.NicTargets
+			Expect(targets).To(HaveLen(1))
+			Expect(*targets[0].VmReference).To(Equal(*mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn).ExtId))
+		})
+
+		It("should skip nodes whose VM cannot be resolved", func() {
+			broken := nodes[1].DeepCopy()
+			broken.Spec.ProviderID = "invalid"
+			service := newService("web", httpPort)
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, []*v1.Node{nodes[0], broken})
+			Expect(err).ToNot(HaveOccurred())
+			baseName := ntnxCloud.GetLoadBalancerName(ctx, mock.MockCluster, service)
+			Expect(sessionsByName()[baseName+"-tcp-80"].TargetsConfig.NicTargets).To(HaveLen(1))
+
+			_, err = ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, []*v1.Node{broken})
+			Expect(err).To(MatchError(ContainSubstring("no load balancer target found")))
+		})
+
+		It("should reconcile targets and ports", func() {
+			service := newService("web", httpPort, dnsPort)
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).ToNot(HaveOccurred())
+			baseName := ntnxCloud.GetLoadBalancerName(ctx, mock.MockCluster, service)
+
+			Expect(ntnxCloud.UpdateLoadBalancer(ctx, mock.MockCluster, service, nodes[:1])).To(Succeed())
+			sessions := sessionsByName()
+			Expect(sessions[baseName+"-tcp-80"].TargetsConfig.NicTargets).To(HaveLen(1))
+			Expect(sessions[baseName+"-udp-53"].TargetsConfig.NicTargets).To(HaveLen(1))
+
+			service.Spec.Ports = []v1.ServicePort{httpPort}
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes[:1])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal(mock.MockLoadBalancerVIP))
+			sessions = sessionsByName()
+			Expect(sessions).To(HaveLen(1))
+			Expect(sessions).To(HaveKey(baseName + "-tcp-80"))
+		})
+
+		It("should delete all sessions of the service", func() {
+			service := newService("web", httpPort, dnsPort)
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(ntnxCloud.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, service)).To(Succeed())
+			Expect(mockEnvironment.GetLoadBalancerSessions()).To(BeEmpty())
+			_, found, err := ntnxCloud.GetLoadBalancer(ctx, mock.MockCluster, service)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should use a static virtual IP from the IP pools", func() {
+			lbConfig.IPPools = []config.IPPool{{Name: "vpc", Addresses: []string{"10.20.0.5"}}}
+			ntnxCloud = newCloud()
+			service := newService("web", httpPort)
+			status, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(status.Ingress[0].IP).To(Equal("10.20.0.5"))
+
+			sessions := mockEnvironment.GetLoadBalancerSessions()
+			Expect(sessions).To(HaveLen(1))
+			Expect(*sessions[0].Listener.VirtualIP.AssignmentType).To(Equal(networkingModels.ASSIGNMENTTYPE_STATIC))
+
+			Expect(ntnxCloud.EnsureLoadBalancerDeleted(ctx, mock.MockCluster, service)).To(Succeed())
+			_, found, err := ntnxCloud.GetLoadBalancer(ctx, mock.MockCluster, service)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(BeFalse())
+		})
+
+		It("should reject unsupported protocols", func() {
+			service := newService("sctp", v1.ServicePort{Protocol: v1.ProtocolSCTP, Port: 9000, NodePort: 30900})
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should garbage collect orphaned sessions of the cluster only", func() {
+			orphanUID := uuid.NewString()
+			orphan := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "gone", Namespace: "default", UID: types.UID(orphanUID)}}
+			otherCluster := "mock-cluster-other"
+			mockEnvironment.AddLoadBalancerSession(&networkingModels.LoadBalancerSession{
+				ExtId:       ptr.To(uuid.NewString()),
+				Name:        ptr.To(loadBalancerName(mock.MockCluster, orphan) + "-tcp-80"),
+				Description: ptr.To(loadBalancerDescription(mock.MockCluster, orphan)),
+			})
+			mockEnvironment.AddLoadBalancerSession(&networkingModels.LoadBalancerSession{
+				ExtId:       ptr.To(uuid.NewString()),
+				Name:        ptr.To(loadBalancerName(otherCluster, orphan) + "-tcp-80"),
+				Description: ptr.To(loadBalancerDescription(otherCluster, orphan)),
+			})
+
+			service := newService("web", httpPort)
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).ToNot(HaveOccurred())
+
+			sessions := sessionsByName()
+			Expect(sessions).To(HaveLen(2))
+			Expect(sessions).To(HaveKey(loadBalancerName(mock.MockCluster, service) + "-tcp-80"))
+			Expect(sessions).To(HaveKey(loadBalancerName(otherCluster, orphan) + "-tcp-80"))
+		})
+
+		It("should not garbage collect sessions of services created while collecting", func() {
+			created := &v1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "created", Namespace: "default", UID: types.UID(uuid.NewString())},
+				Spec:       v1.ServiceSpec{Type: v1.ServiceTypeLoadBalancer},
+			}
+			createdSession := loadBalancerName(mock.MockCluster, created) + "-tcp-80"
+			listed := false
+			// Another worker creates a service and its sessions right after the services are listed
+			kClient.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if listed {
+					return false, nil, nil
+				}
+				listed = true
+				services, err := kClient.Tracker().List(v1.SchemeGroupVersion.WithResource("services"), v1.SchemeGroupVersion.WithKind("Service"), v1.NamespaceAll)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(kClient.Tracker().Add(created)).To(Succeed())
+				mockEnvironment.AddLoadBalancerSession(&networkingModels.LoadBalancerSession{
+					ExtId:       ptr.To(uuid.NewString()),
+					Name:        ptr.To(createdSession),
+					Description: ptr.To(loadBalancerDescription(mock.MockCluster, created)),
+				})
+				return true, services, nil
+			})
+
+			service := newService("web", httpPort)
+			_, err := ntnxCloud.EnsureLoadBalancer(ctx, mock.MockCluster, service, nodes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(listed).To(BeTrue())
+			Expect(sessionsByName()).To(HaveKey(createdSession))
 		})
 	})
 })
@@ -0,0 +1,472 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	networkingCommonModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/common/v1/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+const (
+	loadBalancerGCInterval = 10 * time.Minute
+
+	healthCheckIntervalSecs     = 5
+	healthCheckTimeoutSecs      = 2
+	healthCheckSuccessThreshold = 2
+	healthCheckFailureThreshold = 3
+)
+
+// flowVPCLoadBalancer implements Services of type LoadBalancer with Flow Virtual Networking
+// load balancer sessions. A session only has a single listener protocol, so one session is
+// created per Service port, all sharing the same virtual IP and targeting the node port on
+// the NICs of the node VMs.
+type flowVPCLoadBalancer struct {
+	manager *nutanixManager
+	config  *config.FlowVPCLoadBalancer
+	// vipAllocator is optional. Prism assigns the virtual IP if no IP pools are configured.
+	vipAllocator *vipAllocator
+
+	gcLock sync.Mutex
+	lastGC time.Time
+}
+
+func newFlowVPCLoadBalancer(manager *nutanixManager, lbConfig *config.LoadBalancer, allocator *vipAllocator) (*flowVPCLoadBalancer, error) {
+	if lbConfig == nil || lbConfig.FlowVPC == nil {
+		return nil, fmt.Errorf("flowVPC config cannot be nil when creating Flow VPC load balancer")
+	}
+	return &flowVPCLoadBalancer{
+		manager:      manager,
+		config:       lbConfig.FlowVPC,
+		vipAllocator: allocator,
+	}, nil
+}
+
+// get returns the status of the sessions of the service.
+func (l *flowVPCLoadBalancer) get(ctx context.Context, clusterName string, service *v1.Service) (*v1.LoadBalancerStatus, bool, error) {
+	prism, err := l.manager.nutanixClient.Get()
+	if err != nil {
+		return nil, false, err
+	}
+	sessions, err := l.listServiceSessions(ctx, prism, clusterName, service)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(sessions) == 0 {
+		return nil, false, nil
+	}
+	vip := sessionsVirtualIP(sessions)
+	if vip == "" {
+		return &v1.LoadBalancerStatus{}, true, nil
+	}
+	return &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: vip}}}, true, nil
+}
+
+// ensure creates, updates and deletes the sessions of the service to match its ports and the given nodes.
+func (l *flowVPCLoadBalancer) ensure(ctx context.Context, clusterName string, service *v1.Service, nodes []*v1.Node) (*v1.LoadBalancerStatus, error) {
+	if service == nil {
+		return nil, fmt.Errorf("service cannot be nil when ensuring load balancer")
+	}
+	prism, err := l.manager.nutanixClient.Get()
+	if err != nil {
+		return nil, err
+	}
+	targets, err := l.nicTargets(ctx, nodes)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := l.listServiceSessions(ctx, prism, clusterName, service)
+	if err != nil {
+		return nil, err
+	}
+
+	vip := sessionsVirtualIP(existing)
+	if l.vipAllocator != nil {
+		allocation, err := l.vipAllocator.allocate(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+		vip = allocation.IP
+	}
+
+	baseName := loadBalancerName(clusterName, service)
+	for _, port := range service.Spec.Ports {
+		desired, err := l.desiredSession(clusterName, service, port, targets, vip)
+		if err != nil {
+			return nil, err
+		}
+		name := *desired.Name
+		current, ok := existing[name]
+		delete(existing, name)
+		switch {
+		case !ok:
+			klog.Infof("creating load balancer session %s for service %s", name, serviceKey(service)) //nolint:typecheck
+			if err := prism.CreateLoadBalancerSession(ctx, desired); err != nil {
+				return nil, err
+			}
+		case sessionSpec(&current) != sessionSpec(desired):
+			klog.Infof("updating load balancer session %s for service %s", name, serviceKey(service)) //nolint:typecheck
+			if err := prism.UpdateLoadBalancerSession(ctx, *current.ExtId, desired); err != nil {
+				return nil, err
+			}
+		}
+
+		if vip == "" {
+			// The first session got a virtual IP assigned by Prism, reuse it for the other ports
+			sessions, err := l.listServiceSessions(ctx, prism, clusterName, service)
+			if err != nil {
+				return nil, err
+			}
+			vip = sessionsVirtualIP(sessions)
+			if vip == "" {
+				return nil, fmt.Errorf("virtual IP of load balancer session %s has not been assigned yet", name)
+			}
+		}
+	}
+
+	// Remove sessions of ports that were removed from the service
+	for name, session := range existing {
+		klog.Infof("deleting load balancer session %s of service %s", name, serviceKey(service)) //nolint:typecheck
+		if err := prism.DeleteLoadBalancerSession(ctx, *session.ExtId); err != nil {
+			return nil, err
+		}
+	}
+
+	klog.V(1).InfoS("EnsureLoadBalancer", "service", serviceKey(service), "loadBalancer", baseName, "ip", vip, "targets", len(targets)) //nolint:typecheck
+	l.garbageCollect(ctx, prism, clusterName)
+	if vip == "" {
+		return &v1.LoadBalancerStatus{}, nil
+	}
+	return &v1.LoadBalancerStatus{Ingress: []v1.LoadBalancerIngress{{IP: vip}}}, nil
+}
+
+// delete removes all sessions of the service and releases its virtual IP.
+func (l *flowVPCLoadBalancer) delete(ctx context.Context, clusterName string, service *v1.Service) error {
+	prism, err := l.manager.nutanixClient.Get()
+	if err != nil {
+		return err
+	}
+	sessions, err := l.listServiceSessions(ctx, prism, clusterName, service)
+	if err != nil {
+		return err
+	}
+	for name, session := range sessions {
+		klog.Infof("deleting load balancer session %s of service %s", name, serviceKey(service)) //nolint:typecheck
+		if err := prism.DeleteLoadBalancerSession(ctx, *session.ExtId); err != nil {
+			return err
+		}
+	}
+	if l.vipAllocator != nil {
+		return l.vipAllocator.release(ctx, service)
+	}
+	return nil
+}
+
+// garbageCollect deletes sessions of this cluster whose Service does not exist anymore or is
+// not of type LoadBalancer, e.g. because the Service finalizer was removed by hand.
+// It runs at most once per loadBalancerGCInterval and only logs failures. Sessions are listed
+// before Services, so that the Service of a session created in between is always seen.
+func (l *flowVPCLoadBalancer) garbageCollect(ctx context.Context, prism interfaces.Prism, clusterName string) {
+	l.gcLock.Lock()
+	if time.Since(l.lastGC) < loadBalancerGCInterval {
+		l.gcLock.Unlock()
+		return
+	}
+	l.lastGC = time.Now()
+	l.gcLock.Unlock()
+
+	if l.manager.client == nil {
+		return
+	}
+	prefix := loadBalancerNamePrefix(clusterName)
+	sessions, err := prism.ListLoadBalancerSessions(ctx, loadBalancerNameFilter(prefix))
+	if err != nil {
+		klog.Errorf("failed to list load balancer sessions for garbage collection: %v", err) //nolint:typecheck
+		return
+	}
+	services, err := l.manager.client.CoreV1().Services(v1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		klog.Errorf("failed to list services for load balancer garbage collection: %v", err) //nolint:typecheck
+		return
+	}
+	liveUIDs := make(map[string]struct{}, len(services.Items))
+	for _, service := range services.Items {
+		if service.Spec.Type == v1.ServiceTypeLoadBalancer {
+			liveUIDs[string(service.UID)] = struct{}{}
+		}
+	}
+	for _, session := range sessions {
+		if session.Name == nil || session.ExtId == nil || !isClusterSession(&session, clusterName) {
+			continue
+		}
+		serviceUID, ok := serviceUIDFromSessionName(*session.Name, prefix)
+		if !ok {
+			continue
+		}
+		if _, ok := liveUIDs[serviceUID]; ok {
+			continue
+		}
+		klog.Infof("deleting orphaned load balancer session %s", *session.Name) //nolint:typecheck
+		if err := prism.DeleteLoadBalancerSession(ctx, *session.ExtId); err != nil {
+			klog.Errorf("failed to delete orphaned load balancer session %s: %v", *session.Name, err) //nolint:typecheck
+		}
+	}
+}
+
+// listServiceSessions returns the sessions of the service keyed by name.
+func (l *flowVPCLoadBalancer) listServiceSessions(ctx context.Context, prism interfaces.Prism, clusterName string, service *v1.Service) (map[string]networkingModels.LoadBalancerSession, error) {
+	if service == nil || service.UID == "" {
+		return nil, fmt.Errorf("service must have a UID to look up its load balancer")
+	}
+	prefix := loadBalancerName(clusterName, service) + "-"
+	sessions, err := prism.ListLoadBalancerSessions(ctx, loadBalancerNameFilter(prefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list load balancer sessions of service %s: %w", serviceKey(service), err)
+	}
+	serviceSessions := make(map[string]networkingModels.LoadBalancerSession)
+	for _, session := range sessions {
+		if session.Name == nil || session.ExtId == nil || !strings.HasPrefix(*session.Name, prefix) {
+			continue
+		}
+		serviceSessions[*session.Name] = session
+	}
+	return serviceSessions, nil
+}
+
+type nicTarget struct {
+	vmUUID  string
+	nicUUID string
+}
+
+// nicTargets resolves the node VMs and selects the NIC that should receive load balancer traffic.
+// The VPC is managed by the first Prism Central, so VMs managed by another one cannot be targets.
+// Nodes whose VM cannot be resolved are skipped, so that a single broken node does not take the
+// load balancer down. It only fails if no node remains as target.
+func (l *flowVPCLoadBalancer) nicTargets(ctx context.Context, nodes []*v1.Node) ([]nicTarget, error) {
+	vpcPrismCentral := l.manager.nutanixClient.PrismCentrals()[0]
+	targets := make([]nicTarget, 0, len(nodes))
+	var errs []error
+	for _, node := range nodes {
+		vm, _, err := l.manager.getNodeVM(ctx, node)
+		if err != nil {
+			err = fmt.Errorf("failed to get VM of node %s: %w", node.Name, err)
+			klog.Warningf("%v, skipping it as load balancer target", err) //nolint:typecheck
+			errs = append(errs, err)
+			continue
+		}
+		vmUUID := *vm.ExtId
+		if prismCentral, _ := l.manager.vmPrismCentrals.Load(vmUUID); prismCentral != vpcPrismCentral {
+			klog.Warningf("VM %s of node %s is managed by Prism Central %s instead of the Prism Central of the VPC, skipping it as load balancer target", vmUUID, node.Name, prismCentral) //nolint:typecheck
+			continue
+		}
+		nicUUID := l.backendNic(vm)
+		if nicUUID == "" {
+			klog.Warningf("no suitable NIC found on VM %s of node %s, skipping it as load balancer target", vmUUID, node.Name) //nolint:typecheck
+			continue
+		}
+		targets = append(targets, nicTarget{vmUUID: vmUUID, nicUUID: nicUUID})
+	}
+	if len(targets) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("no load balancer target found: %w", errors.Join(errs...))
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].vmUUID < targets[j].vmUUID
+	})
+	return targets, nil
+}
+
+// backendNic returns the NIC attached to the backend subnet, or the first NIC if no backend subnet is configured.
+func (l *flowVPCLoadBalancer) backendNic(vm *vmmModels.Vm) string {
+	for _, nic := range vm.Nics {
+		if nic.ExtId == nil {
+			continue
+		}
+		if l.config.BackendSubnetUUID == "" || nicSubnetUUID(nic) == l.config.BackendSubnetUUID {
+			return *nic.ExtId
+		}
+	}
+	return ""
+}
+
+func (l *flowVPCLoadBalancer) desiredSession(clusterName string, service *v1.Service, port v1.ServicePort, targets []nicTarget, vip string) (*networkingModels.LoadBalancerSession, error) {
+	var protocol networkingModels.Protocol
+	switch port.Protocol {
+	case v1.ProtocolTCP, "":
+		protocol = networkingModels.PROTOCOL_TCP
+	case v1.ProtocolUDP:
+		protocol = networkingModels.PROTOCOL_UDP
+	default:
+		return nil, fmt.Errorf("protocol %s of service %s is not supported by Flow VPC load balancers", port.Protocol, serviceKey(service))
+	}
+	if port.NodePort == 0 {
+		return nil, fmt.Errorf("port %d of service %s has no node port allocated", port.Port, serviceKey(service))
+	}
+
+	virtualIP := networkingModels.NewVirtualIP()
+	virtualIP.SubnetReference = ptr.To(l.config.VIPSubnetUUID)
+	virtualIP.AssignmentType = networkingModels.ASSIGNMENTTYPE_DYNAMIC.Ref()
+	if vip != "" {
+		virtualIP.AssignmentType = networkingModels.ASSIGNMENTTYPE_STATIC.Ref()
+		virtualIP.IpAddress = &networkingCommonModels.IPAddress{
+			Ipv4: &networkingCommonModels.IPv4Address{Value: ptr.To(vip)},
+		}
+	}
+
+	nicTargets := make([]networkingModels.NicTarget, 0, len(targets))
+	for _, target := range targets {
+		nicTargets = append(nicTargets, networkingModels.NicTarget{
+			VmReference:         ptr.To(target.vmUUID),
+			VirtualNicReference: ptr.To(target.nicUUID),
+			Port:                ptr.To(int(port.NodePort)),
+		})
+	}
+
+	session := networkingModels.NewLoadBalancerSession()
+	session.Name = ptr.To(fmt.Sprintf("%s-%s-%d", loadBalancerName(clusterName, service), strings.ToLower(string(protocol.GetName())), port.Port))
+	session.Description = ptr.To(loadBalancerDescription(clusterName, service))
+	session.VpcReference = ptr.To(l.config.VPCUUID)
+	session.Type = networkingModels.LOADBALANCERSESSIONTYPE_NETWORK_LOAD_BALANCER.Ref()
+	session.Algorithm = networkingModels.ALGORITHM_FIVE_TUPLE_HASH.Ref()
+	session.HealthCheckConfig = &networkingModels.HealthCheck{
+		IntervalSecs:     ptr.To(healthCheckIntervalSecs),
+		TimeoutSecs:      ptr.To(healthCheckTimeoutSecs),
+		SuccessThreshold: ptr.To(healthCheckSuccessThreshold),
+		FailureThreshold: ptr.To(healthCheckFailureThreshold),
+	}
+	session.Listener = &networkingModels.Listener{
+		Protocol: protocol.Ref(),
+		PortRanges: []networkingModels.PortRange{
+			{StartPort: ptr.To(int(port.Port)), EndPort: ptr.To(int(port.Port))},
+		},
+		VirtualIP: virtualIP,
+	}
+	session.TargetsConfig = &networkingModels.Target{NicTargets: nicTargets}
+	return session, nil
+}
+
+// sessionSpec renders the fields of a session managed by the CCM, so that sessions can be compared
+// regardless of fields populated by Prism. The virtual IP assignment type is ignored as a
+// dynamically assigned IP is kept by subsequent updates.
+func sessionSpec(session *networkingModels.LoadBalancerSession) string {
+	var b strings.Builder
+	if session.Listener != nil {
+		if session.Listener.Protocol != nil {
+			fmt.Fprintf(&b, "protocol=%s;", session.Listener.Protocol.GetName())
+		}
+		for _, portRange := range session.Listener.PortRanges {
+			fmt.Fprintf(&b, "ports=%d-%d;", ptr.Deref(portRange.StartPort, 0), ptr.Deref(portRange.EndPort, 0))
+		}
+		if vip := session.Listener.VirtualIP; vip != nil {
+			fmt.Fprintf(&b, "subnet=%s;vip=%s;", ptr.Deref(vip.SubnetReference, ""), virtualIPAddress(vip))
+		}
+	}
+	if session.TargetsConfig != nil {
+		targets := make([]string, 0, len(session.TargetsConfig.NicTargets))
+		for _, t := range session.TargetsConfig.NicTargets {
+			targets = append(targets, fmt.Sprintf("%s/%s:%d", ptr.Deref(t.VmReference, ""), ptr.Deref(t.VirtualNicReference, ""), ptr.Deref(t.Port, 0)))
+		}
+		sort.Strings(targets)
+		fmt.Fprintf(&b, "targets=%s;", strings.Join(targets, ","))
+	}
+	return b.String()
+}
+
+// sessionsVirtualIP returns the virtual IP shared by the sessions of a service, if any is assigned.
+func sessionsVirtualIP(sessions map[string]networkingModels.LoadBalancerSession) string {
+	names := make([]string, 0, len(sessions))
+	for name := range sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		session := sessions[name]
+		if session.Listener == nil || session.Listener.VirtualIP == nil {
+			continue
+		}
+		if ip := virtualIPAddress(session.Listener.VirtualIP); ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+func virtualIPAddress(vip *networkingModels.VirtualIP) string {
+	if vip.IpAddress == nil || vip.IpAddress.Ipv4 == nil {
+		return ""
+	}
+	return ptr.Deref(vip.IpAddress.Ipv4.Value, "")
+}
+
+func nicSubnetUUID(nic vmmModels.Nic) string {
+	if nic.NicNetworkInfo == nil {
+		return ""
+	}
+	switch netInfo := nic.NicNetworkInfo.GetValue().(type) {
+	case vmmModels.VirtualEthernetNicNetworkInfo:
+		if netInfo.Subnet != nil {
+			return ptr.Deref(netInfo.Subnet.ExtId, "")
+		}
+	case vmmModels.DpOffloadNicNetworkInfo:
+		if netInfo.Subnet != nil {
+			return ptr.Deref(netInfo.Subnet.ExtId, "")
+		}
+	}
+	return ""
+}
+
+func loadBalancerDescription(clusterName string, service *v1.Service) string {
+	return fmt.Sprintf("Kubernetes service %s in cluster %s", serviceKey(service), clusterName)
+}
+
+// isClusterSession checks the session description, as sanitized and truncated cluster names in
+// the session name prefix may be shared by several clusters.
+func isClusterSession(session *networkingModels.LoadBalancerSession, clusterName string) bool {
+	return session.Description != nil && strings.HasSuffix(*session.Description, " in cluster "+clusterName)
+}
+
+// serviceUIDFromSessionName extracts the Service UID from a session name of the form <prefix><uid>-<protocol>-<port>.
+func serviceUIDFromSessionName(name string, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, prefix)
+	if !ok || len(rest) < len(uuid.Nil.String()) {
+		return "", false
+	}
+	serviceUID := rest[:len(uuid.Nil.String())]
+	if _, err := uuid.Parse(serviceUID); err != nil {
+		return "", false
+	}
+	return serviceUID, true
+}
+
+func loadBalancerNameFilter(prefix string) string {
+	return fmt.Sprintf("startswith(name, '%s')", prefix)
+}
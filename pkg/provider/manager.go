@@ -18,23 +18,33 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"net/netip"
+	"slices"
+	"sort"
 	"strings"
-
-	convergedV4 "github.com/nutanix-cloud-native/prism-go-client/converged/v4"
-	prismclientv4 "github.com/nutanix-cloud-native/prism-go-client/v4"
+	"sync"
 
 	set "github.com/hashicorp/go-set/v3"
 	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"go4.org/netipx"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/client-go/informers"
 	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	cloudprovider "k8s.io/cloud-provider"
-	"k8s.io/cloud-provider/node/helpers"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
@@ -42,59 +52,204 @@ import (
 )
 
 type nutanixManager struct {
-	client         clientset.Interface
-	config         config.Config
-	nutanixClient  interfaces.Client
-	ignoredNodeIPs *netipx.IPSet
+	client        clientset.Interface
+	nutanixClient interfaces.Client
+	// mu guards the config and the settings parsed from it, which are replaced when the config
+	// file is reloaded
+	mu               sync.RWMutex
+	config           config.Config
+	ignoredNodeIPs   *netipx.IPSet
+	nodeAddressRules []nodeAddressRule
+	nodeDNSTemplates []nodeDNSTemplate
+	// circuitBreakers of the Prism Centrals, empty if disabled
+	circuitBreakers []*circuitBreaker
+	// tracer traces the InstancesV2 operations, nil disables tracing
+	tracer trace.Tracer
+	// tracerShutdown flushes and releases the exporter of the tracer
+	tracerShutdown tracerShutdownFunc
+	// vmPrismCentrals maps the UUIDs of VMs found by getVM to the name of their Prism Central
+	vmPrismCentrals sync.Map
+}
+
+// nodeAddressRule is a parsed config.NodeAddressRule.
+type nodeAddressRule struct {
+	addressType v1.NodeAddressType
+	ips         *netipx.IPSet
+	subnetNames []string
+	subnetUUIDs []string
+}
+
+// nodeSettings are the settings parsed from the config which are replaced together with it.
+type nodeSettings struct {
+	ignoredNodeIPs   *netipx.IPSet
+	nodeAddressRules []nodeAddressRule
+	nodeDNSTemplates []nodeDNSTemplate
+}
+
+func parseNodeSettings(config config.Config) (nodeSettings, error) {
+	ignoredIPSet, err := parseIPSet("ignoredNodeIPs", config.IgnoredNodeIPs)
+	if err != nil {
+		return nodeSettings{}, err
+	}
+
+	nodeAddressRules := make([]nodeAddressRule, 0, len(config.NodeAddressRules))
+	for i, rule := range config.NodeAddressRules {
+		ips, err := parseIPSet(fmt.Sprintf("nodeAddressRules[%d].cidrs", i), rule.CIDRs)
+		if err != nil {
+			return nodeSettings{}, err
+		}
+		nodeAddressRules = append(nodeAddressRules, nodeAddressRule{
+			addressType: v1.NodeAddressType(rule.Type),
+			ips:         ips,
+			subnetNames: rule.SubnetNames,
+			subnetUUIDs: rule.SubnetUUIDs,
+		})
+	}
+
+	nodeDNSTemplates, err := parseNodeDNSTemplates(config.NodeDNS)
+	if err != nil {
+		return nodeSettings{}, err
+	}
+	return nodeSettings{
+		ignoredNodeIPs:   ignoredIPSet,
+		nodeAddressRules: nodeAddressRules,
+		nodeDNSTemplates: nodeDNSTemplates,
+	}, nil
 }
 
 func newNutanixManager(config config.Config) (*nutanixManager, error) {
 	klog.V(1).Info("Creating new newNutanixManager") //nolint:typecheck
+	registerMetrics()
 
-	// Initialize the ignoredNodeIPs set
-	ignoredIPsBuilder := netipx.IPSetBuilder{}
-	for _, ip := range config.IgnoredNodeIPs {
-		switch {
-		case strings.Contains(ip, "-"):
-			ipRange, err := netipx.ParseIPRange(ip)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse ignoredNodeIPs IP range %q: %v", ip, err)
-			}
-			ignoredIPsBuilder.AddRange(ipRange)
-		case strings.Contains(ip, "/"):
-			prefix, err := netip.ParsePrefix(ip)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse ignoredNodeIPs IP prefix %q: %v", ip, err)
-			}
-			ignoredIPsBuilder.AddPrefix(prefix)
-		default:
-			parsedIP, err := netip.ParseAddr(ip)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse ignoredNodeIPs IP %q: %v", ip, err)
-			}
-			ignoredIPsBuilder.Add(parsedIP)
-		}
+	settings, err := parseNodeSettings(config)
+	if err != nil {
+		return nil, err
 	}
 
-	ignoredIPSet, err := ignoredIPsBuilder.IPSet()
+	tracing, err := tracingConfig(config.Tracing)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build ignoredNodeIPs IP set: %v", err)
+		return nil, err
+	}
+	tracer, tracerShutdown, err := newTracer(context.Background(), tracing)
+	if err != nil {
+		return nil, err
 	}
 
+	nutanixClient := newPrismCentralClients(config, tracer, clock.RealClock{})
 	m := &nutanixManager{
-		config: config,
-		nutanixClient: &nutanixClientEnvironment{
-			config:      config,
-			clientCache: convergedV4.NewClientCache(prismclientv4.WithSessionAuth(true)),
-		},
-		ignoredNodeIPs: ignoredIPSet,
+		config:           config,
+		nutanixClient:    nutanixClient,
+		circuitBreakers:  nutanixClient.circuitBreakers,
+		tracer:           tracer,
+		tracerShutdown:   tracerShutdown,
+		ignoredNodeIPs:   settings.ignoredNodeIPs,
+		nodeAddressRules: settings.nodeAddressRules,
+		nodeDNSTemplates: settings.nodeDNSTemplates,
 	}
 	return m, nil
 }
 
+// getConfig returns the current config of the manager.
+func (n *nutanixManager) getConfig() config.Config {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.config
+}
+
+// getNodeSettings returns the settings parsed from the current config of the manager.
+func (n *nutanixManager) getNodeSettings() nodeSettings {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return nodeSettings{
+		ignoredNodeIPs:   n.ignoredNodeIPs,
+		nodeAddressRules: n.nodeAddressRules,
+		nodeDNSTemplates: n.nodeDNSTemplates,
+	}
+}
+
+// reloadConfig replaces the config of the manager and the settings parsed from it. The current
+// config is kept if the new config is invalid. Settings which are only read when the cloud
+// provider starts keep their current value until the CCM is restarted.
+func (n *nutanixManager) reloadConfig(newConfig config.Config) error {
+	settings, err := parseNodeSettings(newConfig)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if changed := restartRequiredConfigChanges(n.config, newConfig); len(changed) > 0 {
+		klog.Warningf("changes of %s only take effect when the cloud controller manager is restarted", strings.Join(changed, ", ")) //nolint:typecheck
+	}
+	newConfig.PrismCentral = n.config.PrismCentral
+	newConfig.PrismCentrals = n.config.PrismCentrals
+	newConfig.PrismCache = n.config.PrismCache
+	newConfig.PrismClient = n.config.PrismClient
+	newConfig.Tracing = n.config.Tracing
+	newConfig.LoadBalancer = n.config.LoadBalancer
+	newConfig.Routes = n.config.Routes
+	n.config = newConfig
+	n.ignoredNodeIPs = settings.ignoredNodeIPs
+	n.nodeAddressRules = settings.nodeAddressRules
+	n.nodeDNSTemplates = settings.nodeDNSTemplates
+	return nil
+}
+
+// restartRequiredConfigChanges returns the config sections which changed but cannot be reloaded.
+func restartRequiredConfigChanges(oldConfig config.Config, newConfig config.Config) []string {
+	var changed []string
+	for section, equal := range map[string]bool{
+		"prismCentral":  equality.Semantic.DeepEqual(oldConfig.PrismCentral, newConfig.PrismCentral),
+		"prismCentrals": equality.Semantic.DeepEqual(oldConfig.PrismCentrals, newConfig.PrismCentrals),
+		"prismCache":    equality.Semantic.DeepEqual(oldConfig.PrismCache, newConfig.PrismCache),
+		"prismClient":   equality.Semantic.DeepEqual(oldConfig.PrismClient, newConfig.PrismClient),
+		"tracing":       equality.Semantic.DeepEqual(oldConfig.Tracing, newConfig.Tracing),
+		"loadBalancer":  equality.Semantic.DeepEqual(oldConfig.LoadBalancer, newConfig.LoadBalancer),
+		"routes":        equality.Semantic.DeepEqual(oldConfig.Routes, newConfig.Routes),
+	} {
+		if !equal {
+			changed = append(changed, section)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// startSpan starts a span of an operation on the given node.
+func (n *nutanixManager) startSpan(ctx context.Context, operation string, node *v1.Node) (context.Context, trace.Span) {
+	tracer := n.tracer
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer(tracerName)
+	}
+	ctx, span := tracer.Start(ctx, operation)
+	if node != nil {
+		span.SetAttributes(nodeNameAttribute.String(node.Name))
+	}
+	return ctx, span
+}
+
 func (n *nutanixManager) setKubernetesClient(client clientset.Interface) {
 	n.client = client
 	n.setInformers()
+	n.setCircuitBreakerEventRecorder()
+}
+
+// setCircuitBreakerEventRecorder records the circuit breaker state changes as events of the CCM pod.
+func (n *nutanixManager) setCircuitBreakerEventRecorder() {
+	if len(n.circuitBreakers) == 0 {
+		return
+	}
+	pod, err := getCCMPodReference()
+	if err != nil {
+		klog.Warningf("not recording circuit breaker events: %v", err) //nolint:typecheck
+		return
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: n.client.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: constants.ClientName})
+	for _, breaker := range n.circuitBreakers {
+		breaker.setEventRecorder(recorder, pod)
+	}
 }
 
 func (n *nutanixManager) setInformers() {
@@ -118,20 +273,11 @@ func (n *nutanixManager) getInstanceMetadata(ctx context.Context, node *v1.Node)
 	nodeName := node.Name
 	klog.V(1).Infof("fetching instance metadata for node %s", nodeName) //nolint:typecheck
 
-	vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
+	vm, nClient, err := n.getNodeVM(ctx, node)
 	if err != nil {
 		return nil, err
 	}
-
-	providerID, err := n.generateProviderID(ctx, vmUUID)
-	if err != nil {
-		return nil, err
-	}
-	nClient, err := n.nutanixClient.Get()
-	if err != nil {
-		return nil, err
-	}
-	vm, err := nClient.GetVM(ctx, vmUUID)
+	providerID, err := n.generateProviderID(ctx, nClient, vm)
 	if err != nil {
 		return nil, err
 	}
@@ -150,7 +296,7 @@ func (n *nutanixManager) getInstanceMetadata(ctx context.Context, node *v1.Node)
 		return nil, err
 	}
 
-	if n.config.EnableCustomLabeling {
+	if n.isCustomLabelingEnabled(node) {
 		klog.V(1).Infof("adding custom labels %s", nodeName) //nolint:typecheck
 		err = n.addCustomLabelsToNode(ctx, node)
 		if err != nil {
@@ -159,72 +305,83 @@ func (n *nutanixManager) getInstanceMetadata(ctx context.Context, node *v1.Node)
 	}
 	return &cloudprovider.InstanceMetadata{
 		ProviderID:    providerID,
-		InstanceType:  constants.InstanceType,
+		InstanceType:  n.getInstanceType(vm),
 		NodeAddresses: nodeAddresses,
 		Region:        topologyInfo.Region,
 		Zone:          topologyInfo.Zone,
 	}, nil
 }
 
+// isCustomLabelingEnabled returns true if labels are computed for the node, or if labels computed
+// earlier must be removed from the node.
+func (n *nutanixManager) isCustomLabelingEnabled(node *v1.Node) bool {
+	config := n.getConfig()
+	return config.EnableCustomLabeling || config.CategoryLabels != nil || hasManagedNodeLabels(node)
+}
+
 func (n *nutanixManager) addCustomLabelsToNode(ctx context.Context, node *v1.Node) error {
+	labels, err := n.getCustomLabels(ctx, node)
+	if err != nil {
+		return err
+	}
+	return n.updateManagedNodeLabels(ctx, node.Name, labels)
+}
+
+// getCustomLabels returns the Prism placement and category labels of the node.
+func (n *nutanixManager) getCustomLabels(ctx context.Context, node *v1.Node) (map[string]string, error) {
 	var cluster *clusterModels.Cluster
 	var host *clusterModels.Host
 
 	labels := map[string]string{}
 
-	nClient, err := n.nutanixClient.Get()
-	if err != nil {
-		return err
-	}
-
-	providerID, err := n.getNutanixProviderIDForNode(ctx, node)
+	vm, nClient, err := n.getNodeVM(ctx, node)
 	if err != nil {
-		return err
-	}
-	vmUUID := n.stripNutanixIDFromProviderID(providerID)
-	vm, err := nClient.GetVM(ctx, vmUUID)
-	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if vm.Cluster != nil && vm.Cluster.ExtId != nil {
 		cluster, err = nClient.GetCluster(ctx, *vm.Cluster.ExtId)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		if vm.Host != nil && vm.Host.ExtId != nil {
 			host, err = nClient.GetClusterHost(ctx, *vm.Cluster.ExtId, *vm.Host.ExtId)
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	if cluster != nil && cluster.ExtId != nil && cluster.Name != nil {
-		labels[constants.CustomPEUUIDLabel] = *cluster.ExtId
-		labels[constants.CustomPENameLabel] = *cluster.Name
-	}
+	if n.getConfig().EnableCustomLabeling {
+		if cluster != nil && cluster.ExtId != nil && cluster.Name != nil {
+			labels[constants.CustomPEUUIDLabel] = *cluster.ExtId
+			labels[constants.CustomPENameLabel] = *cluster.Name
+		}
 
-	if host != nil && host.ExtId != nil && host.HostName != nil {
-		labels[constants.CustomHostUUIDLabel] = *host.ExtId
-		labels[constants.CustomHostNameLabel] = *host.HostName
+		if host != nil && host.ExtId != nil && host.HostName != nil {
+			labels[constants.CustomHostUUIDLabel] = *host.ExtId
+			labels[constants.CustomHostNameLabel] = *host.HostName
+		}
 	}
 
-	result := helpers.AddOrUpdateLabelsOnNode(n.client, labels, node)
-	if !result {
-		return fmt.Errorf("error occurred while updating labels on node %s", node.Name)
+	categoryLabels, err := n.getCategoryLabels(ctx, nClient, vm, host, cluster)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	maps.Copy(labels, categoryLabels)
+
+	return labels, nil
 }
 
 func (n *nutanixManager) getTopologyCategories() (config.TopologyCategories, error) {
 	topologyCategories := config.TopologyCategories{}
-	configTopologyCategories := n.config.TopologyDiscovery.TopologyCategories
-	if n.config.TopologyDiscovery.Type != config.CategoriesTopologyDiscoveryType {
+	topologyDiscovery := n.getConfig().TopologyDiscovery
+	configTopologyCategories := topologyDiscovery.TopologyCategories
+	if topologyDiscovery.Type != config.CategoriesTopologyDiscoveryType {
 		return topologyCategories, fmt.Errorf("cannot invoke getTopologyCategories if topology discovery type is not %s", config.CategoriesTopologyDiscoveryType)
 	}
-	if n.config.TopologyDiscovery.TopologyCategories == nil {
+	if configTopologyCategories == nil {
 		return topologyCategories, fmt.Errorf("topologyCategories must be set when using categories to discover topology")
 	}
 
@@ -241,18 +398,11 @@ func (n *nutanixManager) getTopologyCategories() (config.TopologyCategories, err
 	return topologyCategories, nil
 }
 
+// nodeExists bypasses the Prism cache, as the node is deleted if its VM is reported missing.
 func (n *nutanixManager) nodeExists(ctx context.Context, node *v1.Node) (bool, error) {
-	vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
+	_, _, err := n.getNodeVM(withoutPrismCache(ctx), node)
 	if err != nil {
-		return false, err
-	}
-	nClient, err := n.nutanixClient.Get()
-	if err != nil {
-		return false, err
-	}
-	_, err = nClient.GetVM(ctx, vmUUID)
-	if err != nil {
-		if !strings.Contains(fmt.Sprint(err), "VM_NOT_FOUND") {
+		if !errors.Is(err, interfaces.ErrNotFound) {
 			return false, err
 		}
 		return false, nil
@@ -260,66 +410,285 @@ func (n *nutanixManager) nodeExists(ctx context.Context, node *v1.Node) (bool, e
 	return true, nil
 }
 
+// isNodeShutdown bypasses the Prism cache, as the node is tainted if its VM is reported shut down.
 func (n *nutanixManager) isNodeShutdown(ctx context.Context, node *v1.Node) (bool, error) {
-	vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
+	vm, _, err := n.getNodeVM(withoutPrismCache(ctx), node)
 	if err != nil {
 		return false, err
 	}
-	nClient, err := n.nutanixClient.Get()
-	if err != nil {
-		return false, err
+	return n.isVMShutdown(vm)
+}
+
+// getNodeVM returns the VM of the node and the client of the Prism Central managing it. The VM is
+// looked up by the provider ID of the node, or by its system UUID if the provider ID is not set yet.
+// If the node has no provider ID and no VM has its system UUID, the configured fallback lookups are
+// tried in order until one of them finds the VM.
+func (n *nutanixManager) getNodeVM(ctx context.Context, node *v1.Node) (*vmmModels.Vm, interfaces.Prism, error) {
+	id, err := n.getNutanixProviderIDForNode(ctx, node)
+	if err == nil {
+		var vm *vmmModels.Vm
+		var nClient interfaces.Prism
+		vm, nClient, err = n.getVM(ctx, id)
+		if err == nil || !errors.Is(err, interfaces.ErrNotFound) {
+			return vm, nClient, err
+		}
 	}
-	vm, err := nClient.GetVM(ctx, vmUUID)
-	if err != nil {
-		return false, err
+
+	vmLookup := n.getConfig().VMLookup
+	if node == nil || node.Spec.ProviderID != "" || vmLookup == nil {
+		return nil, nil, err
 	}
-	if n.isVMShutdown(vm) {
-		return true, nil
+	for _, strategy := range vmLookup.Fallbacks {
+		vm, nClient, lookupErr := n.lookupVM(ctx, node, vmLookup, strategy)
+		if lookupErr != nil {
+			return nil, nil, fmt.Errorf("failed to look up VM of node %s by %s: %w", node.Name, strategy, lookupErr)
+		}
+		if vm != nil {
+			klog.V(1).Infof("found VM %s of node %s by %s", *vm.ExtId, node.Name, strategy) //nolint:typecheck
+			return vm, nClient, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w, and no VM of node %s was found by the fallback lookups", err, node.Name)
+}
+
+// lookupVM returns the VM of the node found by the fallback lookup strategy, or nil if the strategy
+// does not apply to the node or no VM matches. It fails if more than one VM matches.
+func (n *nutanixManager) lookupVM(ctx context.Context, node *v1.Node, vmLookup *config.VMLookup, strategy config.VMLookupStrategy) (*vmmModels.Vm, interfaces.Prism, error) {
+	switch strategy {
+	case config.NameVMLookupStrategy:
+		filter := fmt.Sprintf("name eq '%s'", strings.ReplaceAll(node.Name, "'", "''"))
+		return n.findVM(ctx, filter, func(vm *vmmModels.Vm) bool {
+			return vm.Name != nil && *vm.Name == node.Name
+		})
+	case config.InternalIPVMLookupStrategy:
+		nodeIPs := set.New[netip.Addr](0)
+		for _, address := range node.Status.Addresses {
+			if ip, err := netip.ParseAddr(address.Address); err == nil && address.Type == v1.NodeInternalIP {
+				nodeIPs.Insert(ip.Unmap())
+			}
+		}
+		if nodeIPs.Empty() {
+			return nil, nil, nil
+		}
+		return n.findVM(ctx, vmLookup.InternalIPFilter, func(vm *vmmModels.Vm) bool {
+			return slices.ContainsFunc(vmNicIPs(vm), nodeIPs.Contains)
+		})
+	case config.AnnotationVMLookupStrategy:
+		annotation := vmLookup.Annotation
+		if annotation == "" {
+			annotation = constants.DefaultVMUUIDAnnotation
+		}
+		vmUUID := node.Annotations[annotation]
+		if vmUUID == "" {
+			return nil, nil, nil
+		}
+		vm, nClient, err := n.getVM(ctx, providerID{vmUUID: strings.ToLower(vmUUID)})
+		if errors.Is(err, interfaces.ErrNotFound) {
+			return nil, nil, nil
+		}
+		return vm, nClient, err
 	}
-	return false, nil
+	return nil, nil, nil
 }
 
-func (n *nutanixManager) isVMShutdown(vm *vmmModels.Vm) bool {
-	return *vm.PowerState == vmmModels.POWERSTATE_OFF
+// findVM returns the only VM of all Prism Centrals which is listed with the filter and matches, or nil
+// if no VM matches. The filter narrows down the listed VMs, match must check the VMs regardless.
+func (n *nutanixManager) findVM(ctx context.Context, filter string, match func(vm *vmmModels.Vm) bool) (*vmmModels.Vm, interfaces.Prism, error) {
+	var found *vmmModels.Vm
+	var foundClient interfaces.Prism
+	var foundPrismCentral string
+	var matches []string
+	for _, name := range n.nutanixClient.PrismCentrals() {
+		nClient, err := n.nutanixClient.GetForPrismCentral(name)
+		if err == nil {
+			var vms []vmmModels.Vm
+			if vms, err = nClient.ListVMs(ctx, filter); err == nil {
+				for i := range vms {
+					if vm := &vms[i]; vm.ExtId != nil && match(vm) {
+						found, foundClient, foundPrismCentral = vm, nClient, name
+						matches = append(matches, *vm.ExtId)
+					}
+				}
+				continue
+			}
+		}
+		if name != "" {
+			err = fmt.Errorf("prism central %s: %w", name, err)
+		}
+		return nil, nil, err
+	}
+	switch len(matches) {
+	case 0:
+		return nil, nil, nil
+	case 1:
+		n.vmPrismCentrals.Store(*found.ExtId, foundPrismCentral)
+		return found, foundClient, nil
+	}
+	slices.Sort(matches)
+	return nil, nil, fmt.Errorf("%d VMs match: %s", len(matches), strings.Join(matches, ", "))
 }
 
-func (n *nutanixManager) getNutanixInstanceIDForNode(ctx context.Context, node *v1.Node) (string, error) {
-	if node == nil {
-		return "", fmt.Errorf("node cannot be nil when getting nutanix instance ID for node")
+// getVM returns the VM and the client of the Prism Central managing it. The Prism Centrals are tried
+// in order, starting with the one the VM was last found in, or else the one named by the ID. The VM
+// is only reported as not found if every Prism Central could be asked and none of them knows it.
+func (n *nutanixManager) getVM(ctx context.Context, id providerID) (*vmmModels.Vm, interfaces.Prism, error) {
+	vmUUID := id.vmUUID
+	prismCentrals := n.nutanixClient.PrismCentrals()
+	if len(prismCentrals) > 1 {
+		first, ok := "", false
+		if last, found := n.vmPrismCentrals.Load(vmUUID); found {
+			first, ok = last.(string), true
+		} else if id.prismCentralUUID != "" {
+			first, ok = n.getPrismCentralByUUID(ctx, id.prismCentralUUID)
+		}
+		if i := slices.Index(prismCentrals, first); ok && i > 0 {
+			prismCentrals = append(append([]string{first}, prismCentrals[:i]...), prismCentrals[i+1:]...)
+		}
 	}
 
-	nodeUUID := node.Status.NodeInfo.SystemUUID
-	if nodeUUID == "" {
-		return "", fmt.Errorf("failed to retrieve node UUID for node with name %s", node.Name)
+	var errs []error
+	var notFoundErr error
+	for _, name := range prismCentrals {
+		nClient, err := n.nutanixClient.GetForPrismCentral(name)
+		if err == nil {
+			var vm *vmmModels.Vm
+			if vm, err = nClient.GetVM(ctx, vmUUID); err == nil {
+				n.vmPrismCentrals.Store(vmUUID, name)
+				return vm, nClient, nil
+			}
+		}
+		if name != "" {
+			err = fmt.Errorf("prism central %s: %w", name, err)
+		}
+		if errors.Is(err, interfaces.ErrNotFound) {
+			notFoundErr = err
+		} else {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
 	}
-	return strings.ToLower(nodeUUID), nil
+	n.vmPrismCentrals.Delete(vmUUID)
+	return nil, nil, notFoundErr
 }
 
-func (n *nutanixManager) getNutanixProviderIDForNode(ctx context.Context, node *v1.Node) (string, error) {
-	if node == nil {
-		return "", fmt.Errorf("node cannot be nil when fetching providerID")
+// prismForVM returns the client of the Prism Central managing the VM.
+func (n *nutanixManager) prismForVM(ctx context.Context, vm *vmmModels.Vm) (interfaces.Prism, error) {
+	if len(n.nutanixClient.PrismCentrals()) == 1 || vm.ExtId == nil {
+		return n.nutanixClient.Get()
+	}
+	if name, ok := n.vmPrismCentrals.Load(*vm.ExtId); ok {
+		return n.nutanixClient.GetForPrismCentral(name.(string))
 	}
+	_, nClient, err := n.getVM(ctx, providerID{vmUUID: *vm.ExtId})
+	return nClient, err
+}
 
-	providerID := node.Spec.ProviderID
-	if providerID == "" {
-		vmUUID, err := n.getNutanixInstanceIDForNode(ctx, node)
+// getPrismCentralByUUID returns the name of the Prism Central with the given UUID. Prism Centrals
+// which cannot be asked for their UUID are skipped.
+func (n *nutanixManager) getPrismCentralByUUID(ctx context.Context, uuid string) (string, bool) {
+	for _, name := range n.nutanixClient.PrismCentrals() {
+		nClient, err := n.nutanixClient.GetForPrismCentral(name)
 		if err != nil {
-			return "", err
+			continue
 		}
-		providerID, err = n.generateProviderID(ctx, vmUUID)
+		pc, err := n.getPrismCentralCluster(ctx, nClient)
 		if err != nil {
-			return "", err
+			klog.V(1).Infof("failed to get UUID of prism central %s: %v", name, err) //nolint:typecheck
+			continue
 		}
+		if pc.ExtId != nil && strings.EqualFold(*pc.ExtId, uuid) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// defaultVMPowerStates are the node states of the power states which are not mapped by the config.
+var defaultVMPowerStates = map[vmmModels.PowerState]config.NodeState{
+	vmmModels.POWERSTATE_ON:           config.RunningNodeState,
+	vmmModels.POWERSTATE_OFF:          config.ShutdownNodeState,
+	vmmModels.POWERSTATE_PAUSED:       config.ShutdownNodeState,
+	vmmModels.POWERSTATE_UNDETERMINED: config.UnknownNodeState,
+}
+
+// isVMShutdown returns true if the power state of the VM maps to the Shutdown node state. It fails
+// if the VM has no power state, or one which is unknown or maps to the Unknown node state, so that
+// the node lifecycle controller retries instead of guessing.
+func (n *nutanixManager) isVMShutdown(vm *vmmModels.Vm) (bool, error) {
+	vmUUID := ptr.Deref(vm.ExtId, "")
+	if vm.PowerState == nil {
+		return false, fmt.Errorf("VM %s has no power state", vmUUID)
+	}
+	powerState := *vm.PowerState
+	state, ok := n.getConfig().VMPowerStates[powerState.GetName()]
+	if !ok {
+		state, ok = defaultVMPowerStates[powerState]
 	}
-	return providerID, nil
+	if !ok || state == config.UnknownNodeState {
+		return false, fmt.Errorf("cannot determine if VM %s is shut down from its power state %s", vmUUID, powerState.GetName())
+	}
+	return state == config.ShutdownNodeState, nil
 }
 
-func (n *nutanixManager) generateProviderID(ctx context.Context, vmUUID string) (string, error) {
-	if vmUUID == "" {
+// getNutanixInstanceIDForNode returns the ID of the VM of the node, whose UUID is the system UUID of
+// the node. The Prism Central and cluster UUIDs are taken from the provider ID of the node if it is
+// of the qualified format.
+func (n *nutanixManager) getNutanixInstanceIDForNode(ctx context.Context, node *v1.Node) (providerID, error) {
+	if node == nil {
+		return providerID{}, fmt.Errorf("node cannot be nil when getting nutanix instance ID for node")
+	}
+
+	nodeUUID := node.Status.NodeInfo.SystemUUID
+	if nodeUUID == "" {
+		return providerID{}, fmt.Errorf("failed to retrieve node UUID for node with name %s", node.Name)
+	}
+	id := providerID{vmUUID: strings.ToLower(nodeUUID)}
+	if parsed, err := parseProviderID(node.Spec.ProviderID); err == nil && strings.EqualFold(parsed.vmUUID, id.vmUUID) {
+		id.prismCentralUUID = parsed.prismCentralUUID
+		id.clusterUUID = parsed.clusterUUID
+	}
+	return id, nil
+}
+
+// getNutanixProviderIDForNode returns the parsed provider ID of the node, or the ID of its VM if the
+// provider ID is not set yet.
+func (n *nutanixManager) getNutanixProviderIDForNode(ctx context.Context, node *v1.Node) (providerID, error) {
+	if node == nil {
+		return providerID{}, fmt.Errorf("node cannot be nil when fetching providerID")
+	}
+
+	if node.Spec.ProviderID == "" {
+		return n.getNutanixInstanceIDForNode(ctx, node)
+	}
+	return parseProviderID(node.Spec.ProviderID)
+}
+
+// generateProviderID returns the provider ID of the VM in the configured format. The qualified
+// format holds the UUID of the Prism Central of nClient.
+func (n *nutanixManager) generateProviderID(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm) (string, error) {
+	if vm == nil || vm.ExtId == nil || *vm.ExtId == "" {
 		return "", fmt.Errorf("VM UUID cannot be empty when generating nutanix provider ID for node")
 	}
 
-	return fmt.Sprintf("%s://%s", constants.ProviderName, strings.ToLower(vmUUID)), nil
+	id := providerID{vmUUID: strings.ToLower(*vm.ExtId)}
+	if n.getConfig().ProviderIDFormat != config.QualifiedProviderIDFormat {
+		return id.String(), nil
+	}
+	if vm.Cluster == nil || vm.Cluster.ExtId == nil || *vm.Cluster.ExtId == "" {
+		return "", fmt.Errorf("cannot determine cluster of VM %s for its provider ID", id.vmUUID)
+	}
+	pc, err := n.getPrismCentralCluster(ctx, nClient)
+	if err != nil {
+		return "", err
+	}
+	if pc.ExtId == nil || *pc.ExtId == "" {
+		return "", fmt.Errorf("cannot determine UUID of the Prism Central of VM %s for its provider ID", id.vmUUID)
+	}
+	id.prismCentralUUID = strings.ToLower(*pc.ExtId)
+	id.clusterUUID = strings.ToLower(*vm.Cluster.ExtId)
+	return id.String(), nil
 }
 
 func (n *nutanixManager) isNodeAddressesSet(node *v1.Node) bool {
@@ -343,7 +712,7 @@ func (n *nutanixManager) isNodeAddressesSet(node *v1.Node) bool {
 	return hasHostname && hasInternalIP
 }
 
-func (n *nutanixManager) getNodeAddresses(_ context.Context, vm *vmmModels.Vm) ([]v1.NodeAddress, error) {
+func (n *nutanixManager) getNodeAddresses(ctx context.Context, vm *vmmModels.Vm) ([]v1.NodeAddress, error) {
 	var addressSet *set.Set[v1.NodeAddress]
 	var addresses []v1.NodeAddress
 
@@ -356,110 +725,228 @@ func (n *nutanixManager) getNodeAddresses(_ context.Context, vm *vmmModels.Vm) (
 	}
 
 	addressSet = set.From([]v1.NodeAddress{}) //nolint:typecheck
+	subnetNames := make(map[string]string)
 	for _, nic := range vm.Nics {
 		if nic.NicNetworkInfo == nil {
 			continue
 		}
 
-		switch nic.NicNetworkInfo.GetValue().(type) {
+		var nicAddresses []v1.NodeAddress
+		var err error
+		switch netInfo := nic.NicNetworkInfo.GetValue().(type) {
 		case vmmModels.VirtualEthernetNicNetworkInfo:
-			netInfo := nic.NicNetworkInfo.GetValue().(vmmModels.VirtualEthernetNicNetworkInfo)
-			vmAddressSet, err := n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info)
-			if err != nil {
-				return nil, err
-			}
-			addressSet.InsertSlice(vmAddressSet)
-
+			nicAddresses, err = n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info, netInfo.Ipv6Info)
 		case vmmModels.DpOffloadNicNetworkInfo:
-			netInfo := nic.NicNetworkInfo.GetValue().(vmmModels.DpOffloadNicNetworkInfo)
-			vmAddressSet, err := n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info)
-			if err != nil {
-				return nil, err
-			}
-			addressSet.InsertSlice(vmAddressSet)
-
+			nicAddresses, err = n.getNodeAddressesFromNicNetworkInfo(netInfo.Ipv4Config, netInfo.Ipv4Info, netInfo.Ipv6Info)
 		default:
 			klog.V(1).Infof("unsupported NIC network info type: %T", nic.NicNetworkInfo.GetValue()) //nolint:typecheck
 			continue
 		}
+		if err != nil {
+			return nil, err
+		}
+		for _, address := range nicAddresses {
+			address.Type, err = n.getNodeAddressType(ctx, vm, address.Address, nicSubnetUUID(nic), subnetNames)
+			if err != nil {
+				return nil, err
+			}
+			if addressSet.Insert(address) {
+				addresses = append(addresses, address)
+			}
+		}
 	}
 
-	addresses = append(addresses, addressSet.Slice()...)
-
 	if len(addresses) == 0 {
 		return addresses, fmt.Errorf("unable to determine network interfaces from VM with UUID %s", *vm.ExtId)
 	}
+	addresses = n.sortNodeAddressesByIPFamily(addresses)
 
 	addresses = append(addresses, v1.NodeAddress{
 		Type:    v1.NodeHostName,
 		Address: *vm.Name,
 	})
-	return addresses, nil
+
+	dnsAddresses, err := n.getNodeDNSAddresses(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+	return append(addresses, dnsAddresses...), nil
 }
 
-func (n *nutanixManager) getNodeAddressesFromNicNetworkInfo(ipv4Config *vmmModels.Ipv4Config, ipv4Info *vmmModels.Ipv4Info) ([]v1.NodeAddress, error) {
+// getNodeAddressesFromNicNetworkInfo returns the addresses of a NIC in the order primary, secondary
+// and learned addresses. Addresses of disabled IP families and ignored addresses are skipped.
+func (n *nutanixManager) getNodeAddressesFromNicNetworkInfo(ipv4Config *vmmModels.Ipv4Config, ipv4Info *vmmModels.Ipv4Info, ipv6Info *vmmModels.Ipv6Info) ([]v1.NodeAddress, error) {
+	ips := nicNetworkInfoIPs(ipv4Config, ipv4Info, ipv6Info)
+
+	ignoredNodeIPs := n.getNodeSettings().ignoredNodeIPs
 	addressSet := set.From([]v1.NodeAddress{})
+	addresses := make([]v1.NodeAddress, 0, len(ips))
+	for _, ip := range ips {
+		if ip == nil {
+			continue
+		}
+		parsedIP, err := netip.ParseAddr(*ip)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IP address %q: %v", *ip, err)
+		}
+		parsedIP = parsedIP.Unmap()
+		if !isNodeAddressCandidate(parsedIP) || ignoredNodeIPs.Contains(parsedIP) || !n.isIPFamilyEnabled(parsedIP) {
+			continue
+		}
+		address := v1.NodeAddress{
+			Type:    v1.NodeInternalIP,
+			Address: parsedIP.String(),
+		}
+		if addressSet.Insert(address) {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
 
+// nicNetworkInfoIPs returns the IPs of a NIC in the order primary, secondary and learned addresses.
+func nicNetworkInfoIPs(ipv4Config *vmmModels.Ipv4Config, ipv4Info *vmmModels.Ipv4Info, ipv6Info *vmmModels.Ipv6Info) []*string {
+	ips := make([]*string, 0)
 	if ipv4Config != nil {
-		primaryIP := ipv4Config.IpAddress.Value
-		if primaryIP != nil {
-			parsedIP, err := netip.ParseAddr(*primaryIP)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse IP address %q: %v", *primaryIP, err)
-			}
-			if !n.ignoredNodeIPs.Contains(parsedIP) {
-				addressSet.Insert(v1.NodeAddress{
-					Type:    v1.NodeInternalIP,
-					Address: *primaryIP,
-				})
-			}
+		if ipv4Config.IpAddress != nil {
+			ips = append(ips, ipv4Config.IpAddress.Value)
 		}
-
 		for _, ipAddress := range ipv4Config.SecondaryIpAddressList {
-			if ipAddress.Value == nil {
-				continue
-			}
-			parsedIP, err := netip.ParseAddr(*ipAddress.Value)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse IP address %q: %v", *ipAddress.Value, err)
-			}
-			if !n.ignoredNodeIPs.Contains(parsedIP) {
-				addressSet.Insert(v1.NodeAddress{
-					Type:    v1.NodeInternalIP,
-					Address: *ipAddress.Value,
-				})
-			}
+			ips = append(ips, ipAddress.Value)
 		}
 	}
-
 	if ipv4Info != nil {
 		for _, ipAddress := range ipv4Info.LearnedIpAddresses {
-			if ipAddress.Value == nil {
+			ips = append(ips, ipAddress.Value)
+		}
+	}
+	if ipv6Info != nil {
+		for _, ipAddress := range ipv6Info.LearnedIpv6Addresses {
+			ips = append(ips, ipAddress.Value)
+		}
+	}
+	return ips
+}
+
+// isNodeAddressCandidate returns false for link-local, loopback and multicast addresses, which are
+// learned by Prism but do not identify the node.
+func isNodeAddressCandidate(ip netip.Addr) bool {
+	return !ip.IsLinkLocalUnicast() && !ip.IsLoopback() && !ip.IsMulticast()
+}
+
+// vmNicIPs returns the parseable node address candidates of all NICs of the VM.
+func vmNicIPs(vm *vmmModels.Vm) []netip.Addr {
+	var ips []netip.Addr
+	for _, nic := range vm.Nics {
+		if nic.NicNetworkInfo == nil {
+			continue
+		}
+		var nicIPs []*string
+		switch netInfo := nic.NicNetworkInfo.GetValue().(type) {
+		case vmmModels.VirtualEthernetNicNetworkInfo:
+			nicIPs = nicNetworkInfoIPs(netInfo.Ipv4Config, netInfo.Ipv4Info, netInfo.Ipv6Info)
+		case vmmModels.DpOffloadNicNetworkInfo:
+			nicIPs = nicNetworkInfoIPs(netInfo.Ipv4Config, netInfo.Ipv4Info, netInfo.Ipv6Info)
+		}
+		for _, ip := range nicIPs {
+			if ip == nil {
 				continue
 			}
-
-			parsedIP, err := netip.ParseAddr(*ipAddress.Value)
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse IP address %q: %v", *ipAddress.Value, err)
+			if parsedIP, err := netip.ParseAddr(*ip); err == nil && isNodeAddressCandidate(parsedIP.Unmap()) {
+				ips = append(ips, parsedIP.Unmap())
 			}
+		}
+	}
+	return ips
+}
 
-			if !n.ignoredNodeIPs.Contains(parsedIP) {
-				addressSet.Insert(v1.NodeAddress{
-					Type:    v1.NodeInternalIP,
-					Address: *ipAddress.Value,
-				})
+// getNodeAddressType returns the type of the first node address rule matching the address or the
+// subnet of its NIC. Addresses not matching any rule are reported as InternalIP.
+// Subnet names are looked up in Prism only if a rule matches on names, and are cached in subnetNames.
+func (n *nutanixManager) getNodeAddressType(ctx context.Context, vm *vmmModels.Vm, address string, subnetUUID string, subnetNames map[string]string) (v1.NodeAddressType, error) {
+	ip, err := netip.ParseAddr(address)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse IP address %q: %v", address, err)
+	}
+	for _, rule := range n.getNodeSettings().nodeAddressRules {
+		if rule.ips.Contains(ip) {
+			return rule.addressType, nil
+		}
+		if subnetUUID == "" {
+			continue
+		}
+		if slices.Contains(rule.subnetUUIDs, subnetUUID) {
+			return rule.addressType, nil
+		}
+		if len(rule.subnetNames) == 0 {
+			continue
+		}
+		subnetName, ok := subnetNames[subnetUUID]
+		if !ok {
+			subnetName, err = n.getSubnetName(ctx, vm, subnetUUID)
+			if err != nil {
+				return "", err
 			}
+			subnetNames[subnetUUID] = subnetName
+		}
+		if slices.Contains(rule.subnetNames, subnetName) {
+			return rule.addressType, nil
 		}
 	}
-	return addressSet.Slice(), nil
+	return v1.NodeInternalIP, nil
 }
 
-func (n *nutanixManager) stripNutanixIDFromProviderID(providerID string) string {
-	return strings.TrimPrefix(providerID, fmt.Sprintf("%s://", constants.ProviderName))
+func (n *nutanixManager) getSubnetName(ctx context.Context, vm *vmmModels.Vm, subnetUUID string) (string, error) {
+	nClient, err := n.prismForVM(ctx, vm)
+	if err != nil {
+		return "", err
+	}
+	subnet, err := nClient.GetSubnet(ctx, subnetUUID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get subnet %s: %w", subnetUUID, err)
+	}
+	if subnet == nil || subnet.Name == nil {
+		return "", fmt.Errorf("failed to get name of subnet %s", subnetUUID)
+	}
+	return *subnet.Name, nil
+}
+
+// nodeIPFamilies returns the IP families of node addresses in order of preference.
+// Only IPv4 addresses are reported if no families are configured.
+func (n *nutanixManager) nodeIPFamilies() []config.IPFamily {
+	families := n.getConfig().NodeIPFamilies
+	if len(families) == 0 {
+		return []config.IPFamily{config.IPv4IPFamily}
+	}
+	return families
+}
+
+func (n *nutanixManager) isIPFamilyEnabled(ip netip.Addr) bool {
+	return slices.Contains(n.nodeIPFamilies(), ipFamilyOf(ip))
+}
+
+// sortNodeAddressesByIPFamily orders the addresses by the configured IP family preference, as the
+// first InternalIP of a node is used as its primary node IP. The order within a family is kept.
+func (n *nutanixManager) sortNodeAddressesByIPFamily(addresses []v1.NodeAddress) []v1.NodeAddress {
+	families := n.nodeIPFamilies()
+	familyIndex := func(address v1.NodeAddress) int {
+		ip, err := netip.ParseAddr(address.Address)
+		if err != nil {
+			return len(families)
+		}
+		if i := slices.Index(families, ipFamilyOf(ip)); i >= 0 {
+			return i
+		}
+		return len(families)
+	}
+	sort.SliceStable(addresses, func(i, j int) bool {
+		return familyIndex(addresses[i]) < familyIndex(addresses[j])
+	})
+	return addresses
 }
 
 func (n *nutanixManager) getTopologyInfo(ctx context.Context, nutanixClient interfaces.Prism, vm *vmmModels.Vm) (config.TopologyInfo, error) {
-	topologyDiscovery := n.config.TopologyDiscovery
+	topologyDiscovery := n.getConfig().TopologyDiscovery
 
 	switch topologyDiscovery.Type {
 	case config.PrismTopologyDiscoveryType:
@@ -513,13 +1000,17 @@ func (n *nutanixManager) getTopologyInfoUsingCategories(ctx context.Context, nut
 		return *tc, nil
 	}
 	klog.V(1).Infof("searching for topology info on host entity for VM: %s", *vm.Name) //nolint:typecheck
-	nClient, err := n.nutanixClient.Get()
+	err = n.getTopologyInfoFromHost(ctx, nutanixClient, vm, tc)
 	if err != nil {
 		return *tc, err
 	}
+	if !n.hasEmptyTopologyInfo(*tc) {
+		klog.V(1).Infof("topology info was found on host entity: %+v", *tc) //nolint:typecheck
+		return *tc, nil
+	}
 
 	klog.V(1).Infof("searching for topology info on cluster entity for VM: %s", *vm.Name) //nolint:typecheck
-	err = n.getTopologyInfoFromCluster(ctx, nClient, vm, tc)
+	err = n.getTopologyInfoFromCluster(ctx, nutanixClient, vm, tc)
 	if err != nil {
 		return *tc, err
 	}
@@ -593,6 +1084,78 @@ func (n *nutanixManager) getTopologyInfoFromCluster(ctx context.Context, nClient
 	return nil
 }
 
+// getTopologyInfoFromHost sets the topology info from the categories of the AHV host running the
+// VM. Hosts are not tagged in their own model, so their categories are found by their associations.
+func (n *nutanixManager) getTopologyInfoFromHost(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, ti *config.TopologyInfo) error {
+	if nClient == nil {
+		return fmt.Errorf("nutanix client cannot be nil when searching for topology info")
+	}
+	if vm == nil {
+		return fmt.Errorf("vm cannot be nil when searching for topology info")
+	}
+	if ti == nil {
+		return fmt.Errorf("topology categories cannot be nil when searching for topology info")
+	}
+	// Powered off VMs do not run on a host
+	if vm.Host == nil || vm.Host.ExtId == nil || vm.Cluster == nil || vm.Cluster.ExtId == nil {
+		return nil
+	}
+	host, err := nClient.GetClusterHost(ctx, *vm.Cluster.ExtId, *vm.Host.ExtId)
+	if err != nil {
+		return fmt.Errorf("error occurred while searching for topology info on host: %v", err)
+	}
+	hostUUID := ptr.Deref(host.ExtId, *vm.Host.ExtId)
+
+	tCategories, err := n.getTopologyCategories()
+	if err != nil {
+		return err
+	}
+	var keyFilters []string
+	for _, key := range []string{tCategories.RegionCategory, tCategories.ZoneCategory} {
+		if key != "" {
+			keyFilters = append(keyFilters, fmt.Sprintf("key eq '%s'", escapeODataString(key)))
+		}
+	}
+	hostCategories, err := getHostCategoryUUIDs(ctx, nClient, hostUUID, keyFilters)
+	if err != nil {
+		return fmt.Errorf("error occurred while searching for topology info on host: %v", err)
+	}
+	return n.getZoneInfoFromCategories(ctx, nClient, hostCategories, ti)
+}
+
+// getHostCategoryUUIDs returns the UUIDs of the categories associated with the host that match any
+// of the key filters. Hosts do not reference their categories, so they are found by the
+// associations of the categories.
+func getHostCategoryUUIDs(ctx context.Context, nClient interfaces.Prism, hostUUID string, keyFilters []string) ([]string, error) {
+	if len(keyFilters) == 0 {
+		return nil, nil
+	}
+	categories, err := nClient.ListCategories(ctx, strings.Join(keyFilters, " or "))
+	if err != nil {
+		return nil, err
+	}
+
+	hostCategories := make([]string, 0)
+	for _, category := range categories {
+		if category.ExtId == nil {
+			continue
+		}
+		for _, association := range category.DetailedAssociations {
+			if association.ResourceType != nil && *association.ResourceType == prismModels.RESOURCETYPE_HOST &&
+				association.ResourceId != nil && strings.EqualFold(*association.ResourceId, hostUUID) {
+				hostCategories = append(hostCategories, *category.ExtId)
+				break
+			}
+		}
+	}
+	return hostCategories, nil
+}
+
+// escapeODataString escapes a value for a string literal of an OData filter.
+func escapeODataString(value string) string {
+	return strings.ReplaceAll(value, "'", "''")
+}
+
 func (n *nutanixManager) getTopologyInfoFromVM(ctx context.Context, nClient interfaces.Prism, vm *vmmModels.Vm, ti *config.TopologyInfo) error {
 	if vm == nil {
 		return fmt.Errorf("vm cannot be nil when searching for topology info")
@@ -20,15 +20,20 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/netip"
+	"strings"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gstruct"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
 
+	vmmCommonModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/common/v1/config"
 	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
 
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
@@ -39,7 +44,7 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 		ctx             context.Context
 		kClient         *fake.Clientset
 		mockEnvironment *mock.MockEnvironment
-		m               nutanixManager
+		m               *nutanixManager
 		err             error
 		nClient         interfaces.Prism
 	)
@@ -62,13 +67,13 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 						ZoneCategory:   mock.MockDefaultZone,
 					},
 				},
-				IgnoredNodeIPs: []string{"127.100.10.1", "127.200.20.1", "127.200.100.1/24", "127.200.200.1-127.200.200.10"},
+				IgnoredNodeIPs: []string{"10.100.10.1", "10.200.20.1", "10.200.100.1/24", "10.200.200.1-10.200.200.10"},
 			},
 		)
 		Expect(err).ShouldNot(HaveOccurred())
 		mgr.client = kClient
 		mgr.nutanixClient = nutanixClient
-		m = *mgr
+		m = mgr
 	})
 
 	Context("Test HasEmptyTopologyInfo", func() {
@@ -112,6 +117,44 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 			Expect(vm).ToNot(BeNil())
 			Expect(m.isVMShutdown(vm)).To(BeFalse())
 		})
+
+		It("should detect if VM is paused", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			vm.PowerState = vmmModels.POWERSTATE_PAUSED.Ref()
+			Expect(m.isVMShutdown(vm)).To(BeTrue())
+		})
+
+		It("should fail if the power state of the VM is unknown", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			for _, powerState := range []*vmmModels.PowerState{
+				nil,
+				vmmModels.POWERSTATE_UNDETERMINED.Ref(),
+				vmmModels.POWERSTATE_UNKNOWN.Ref(),
+			} {
+				vm.PowerState = powerState
+				_, err := m.isVMShutdown(vm)
+				Expect(err).To(HaveOccurred(), "power state: %v", powerState)
+			}
+		})
+
+		It("should map power states as configured", func() { // nolint:typecheck
+			m.config.VMPowerStates = map[string]config.NodeState{
+				"PAUSED":       config.RunningNodeState,
+				"UNDETERMINED": config.ShutdownNodeState,
+				"OFF":          config.UnknownNodeState,
+			}
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			vm.PowerState = vmmModels.POWERSTATE_PAUSED.Ref()
+			Expect(m.isVMShutdown(vm)).To(BeFalse())
+			vm.PowerState = vmmModels.POWERSTATE_UNDETERMINED.Ref()
+			Expect(m.isVMShutdown(vm)).To(BeTrue())
+			vm.PowerState = vmmModels.POWERSTATE_OFF.Ref()
+			_, err := m.isVMShutdown(vm)
+			Expect(err).To(HaveOccurred())
+		})
 	})
 
 	Context("Test GetNodeAddresses", func() {
@@ -253,19 +296,344 @@ var _ = Describe("Test Manager", func() { // nolint:typecheck
 		})
 	})
 
+	Context("Test GetNodeAddresses with IP families", func() {
+		It("should only return IPv4 addresses by default", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameDualStack)
+			Expect(vm).ToNot(BeNil())
+			addresses, err := m.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should return IPv4 addresses first", func() { // nolint:typecheck
+			m.config.NodeIPFamilies = []config.IPFamily{config.IPv4IPFamily, config.IPv6IPFamily}
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameDualStack)
+			Expect(vm).ToNot(BeNil())
+			addresses, err := m.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeInternalIP, Address: mock.MockIPv6},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should return IPv6 addresses first", func() { // nolint:typecheck
+			m.config.NodeIPFamilies = []config.IPFamily{config.IPv6IPFamily, config.IPv4IPFamily}
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameDualStack)
+			Expect(vm).ToNot(BeNil())
+			addresses, err := m.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIPv6},
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should only return IPv6 addresses", func() { // nolint:typecheck
+			m.config.NodeIPFamilies = []config.IPFamily{config.IPv6IPFamily}
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNameDualStack)
+			Expect(vm).ToNot(BeNil())
+			addresses, err := m.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIPv6},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should filter IPv6 node addresses by prefix and range", func() { // nolint:typecheck
+			for _, ignored := range []string{"fe80::/10", "fe80::-fe80::ffff", mock.MockIPv6LinkLocal} {
+				mgr, err := newNutanixManager(config.Config{
+					NodeIPFamilies: []config.IPFamily{config.IPv6IPFamily, config.IPv4IPFamily},
+					IgnoredNodeIPs: []string{"127.0.0.0/8", ignored},
+				})
+				Expect(err).ShouldNot(HaveOccurred())
+				vm := mockEnvironment.GetVM(ctx, mock.MockVMNameDualStack)
+				Expect(vm).ToNot(BeNil())
+				addresses, err := mgr.getNodeAddresses(ctx, vm)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(addresses).To(Equal([]v1.NodeAddress{
+					{Type: v1.NodeInternalIP, Address: mock.MockIPv6},
+					{Type: v1.NodeInternalIP, Address: mock.MockIP},
+					{Type: v1.NodeHostName, Address: *vm.Name},
+				}), "ignored: %s", ignored)
+			}
+		})
+
+		It("should skip link-local, loopback and multicast addresses", func() { // nolint:typecheck
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			netInfo := vm.Nics[0].NicNetworkInfo.GetValue().(vmmModels.VirtualEthernetNicNetworkInfo)
+			for _, ip := range []string{"169.254.10.1", "127.0.0.1", "224.0.0.1"} {
+				netInfo.Ipv4Info.LearnedIpAddresses = append(netInfo.Ipv4Info.LearnedIpAddresses, vmmCommonModels.IPv4Address{Value: ptr.To(ip)})
+			}
+			Expect(vm.Nics[0].SetNicNetworkInfo(netInfo)).To(Succeed())
+			addresses, err := m.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+			Expect(vmNicIPs(vm)).ToNot(ContainElement(netip.MustParseAddr("169.254.10.1")))
+		})
+
+		It("should fail if only addresses of a disabled family are found", func() { // nolint:typecheck
+			m.config.NodeIPFamilies = []config.IPFamily{config.IPv6IPFamily}
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			_, err := m.getNodeAddresses(ctx, vm)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("Test GetNodeAddresses with node address rules", func() {
+		var vm *vmmModels.Vm
+
+		newManagerWithRules := func(c config.Config) *nutanixManager {
+			mgr, err := newNutanixManager(c)
+			Expect(err).ShouldNot(HaveOccurred())
+			mgr.nutanixClient = m.nutanixClient
+			return mgr
+		}
+
+		BeforeEach(func() {
+			vm = mockEnvironment.GetVM(ctx, mock.MockVMNameMultiSubnet)
+			Expect(vm).ToNot(BeNil())
+		})
+
+		It("should return InternalIP addresses if no rule matches", func() { // nolint:typecheck
+			mgr := newManagerWithRules(config.Config{NodeAddressRules: []config.NodeAddressRule{
+				{Type: config.ExternalIPNodeAddressType, CIDRs: []string{"10.0.0.0/8"}, SubnetUUIDs: []string{"non-existing-subnet"}},
+			}})
+			addresses, err := mgr.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeInternalIP, Address: mock.MockExternalIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		for name, rule := range map[string]config.NodeAddressRule{
+			"CIDR":        {Type: config.ExternalIPNodeAddressType, CIDRs: []string{"4.4.0.0/16"}},
+			"subnet name": {Type: config.ExternalIPNodeAddressType, SubnetNames: []string{mock.MockExternalSubnetName}},
+			"subnet UUID": {Type: config.ExternalIPNodeAddressType, SubnetUUIDs: []string{mock.MockExternalSubnetUUID}},
+		} {
+			It("should classify ExternalIP addresses by "+name, func() { // nolint:typecheck
+				mgr := newManagerWithRules(config.Config{NodeAddressRules: []config.NodeAddressRule{rule}})
+				addresses, err := mgr.getNodeAddresses(ctx, vm)
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(addresses).To(Equal([]v1.NodeAddress{
+					{Type: v1.NodeInternalIP, Address: mock.MockIP},
+					{Type: v1.NodeExternalIP, Address: mock.MockExternalIP},
+					{Type: v1.NodeHostName, Address: *vm.Name},
+				}))
+			})
+		}
+
+		It("should apply the first matching rule", func() { // nolint:typecheck
+			mgr := newManagerWithRules(config.Config{NodeAddressRules: []config.NodeAddressRule{
+				{Type: config.InternalIPNodeAddressType, CIDRs: []string{mock.MockExternalIP}},
+				{Type: config.ExternalIPNodeAddressType, SubnetNames: []string{mock.MockSubnetName, mock.MockExternalSubnetName}},
+			}})
+			addresses, err := mgr.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeExternalIP, Address: mock.MockIP},
+				{Type: v1.NodeInternalIP, Address: mock.MockExternalIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should skip ignored node IPs before applying rules", func() { // nolint:typecheck
+			mgr := newManagerWithRules(config.Config{
+				IgnoredNodeIPs:   []string{mock.MockExternalIP},
+				NodeAddressRules: []config.NodeAddressRule{{Type: config.ExternalIPNodeAddressType, CIDRs: []string{"0.0.0.0/0"}}},
+			})
+			addresses, err := mgr.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeExternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should fail if the subnet name cannot be looked up", func() { // nolint:typecheck
+			mgr := newManagerWithRules(config.Config{NodeAddressRules: []config.NodeAddressRule{
+				{Type: config.ExternalIPNodeAddressType, SubnetNames: []string{mock.MockExternalSubnetName}},
+			}})
+			vm.Nics[1].NicNetworkInfo.GetValue().(vmmModels.VirtualEthernetNicNetworkInfo).Subnet.ExtId = ptr.To("non-existing-subnet")
+			_, err := mgr.getNodeAddresses(ctx, vm)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("Test GetNodeAddresses with DNS names", func() {
+		newManagerWithDNS := func(nodeDNS *config.NodeDNS) (*nutanixManager, error) {
+			mgr, err := newNutanixManager(config.Config{NodeDNS: nodeDNS})
+			if err != nil {
+				return nil, err
+			}
+			mgr.nutanixClient = m.nutanixClient
+			return mgr, nil
+		}
+
+		It("should render InternalDNS and ExternalDNS addresses", func() { // nolint:typecheck
+			mgr, err := newManagerWithDNS(&config.NodeDNS{
+				InternalDNSTemplate: "{{.VMName}}.{{.ClusterName}}.corp.example",
+				ExternalDNSTemplate: "{{.VMName}}.{{.HostUUID}}.Example.COM",
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			addresses, err := mgr.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+				{Type: v1.NodeInternalDNS, Address: fmt.Sprintf("%s.%s.corp.example", mock.MockVMNamePoweredOn, mock.MockCluster)},
+				{Type: v1.NodeExternalDNS, Address: fmt.Sprintf("%s.%s.example.com", mock.MockVMNamePoweredOn, mock.MockHostUUID)},
+			}))
+		})
+
+		It("should skip DNS names rendering empty", func() { // nolint:typecheck
+			mgr, err := newManagerWithDNS(&config.NodeDNS{
+				ExternalDNSTemplate: "{{with .HostUUID}}{{$.VMName}}.{{.}}.example.com{{end}}",
+			})
+			Expect(err).ShouldNot(HaveOccurred())
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOff)
+			Expect(vm).ToNot(BeNil())
+			addresses, err := mgr.getNodeAddresses(ctx, vm)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(addresses).To(Equal([]v1.NodeAddress{
+				{Type: v1.NodeInternalIP, Address: mock.MockIP},
+				{Type: v1.NodeHostName, Address: *vm.Name},
+			}))
+		})
+
+		It("should fail if a template cannot be parsed", func() { // nolint:typecheck
+			_, err := newManagerWithDNS(&config.NodeDNS{InternalDNSTemplate: "{{.VMName"})
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should fail if a template references unknown fields", func() { // nolint:typecheck
+			mgr, err := newManagerWithDNS(&config.NodeDNS{InternalDNSTemplate: "{{.Unknown}}.corp.example"})
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = mgr.getNodeAddresses(ctx, mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn))
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should fail if a template renders an invalid DNS name", func() { // nolint:typecheck
+			mgr, err := newManagerWithDNS(&config.NodeDNS{InternalDNSTemplate: "{{.VMName}}_{{.ClusterName}}"})
+			Expect(err).ShouldNot(HaveOccurred())
+			_, err = mgr.getNodeAddresses(ctx, mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn))
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("Test getInstanceType", func() {
+		var vm *vmmModels.Vm
+
+		BeforeEach(func() {
+			vm = mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			m.config.InstanceTypes = &config.InstanceTypes{}
+		})
+
+		It("should return the static instance type if instance types are not configured", func() { // nolint:typecheck
+			m.config.InstanceTypes = nil
+			Expect(m.getInstanceType(vm)).To(Equal(constants.InstanceType))
+		})
+
+		It("should derive the instance type from the VM shape", func() { // nolint:typecheck
+			Expect(m.getInstanceType(vm)).To(Equal("ahv-4c-8g"))
+
+			vm.NumThreadsPerCore = ptr.To(2)
+			vm.MemorySizeBytes = ptr.To(int64(1536 * 1024 * 1024))
+			vm.Gpus = []vmmModels.Gpu{*vmmModels.NewGpu()}
+			Expect(m.getInstanceType(vm)).To(Equal("ahv-8c-1536m-1gpu"))
+		})
+
+		It("should return the first matching instance class", func() { // nolint:typecheck
+			m.config.InstanceTypes.Classes = []config.InstanceClass{
+				{Name: "gpu", GPUs: ptr.To(1)},
+				{Name: "large", VCPUs: 8},
+				{Name: "medium", VCPUs: 4, MemoryGiB: 8, GPUs: ptr.To(0)},
+				{Name: "small"},
+			}
+			Expect(m.getInstanceType(vm)).To(Equal("medium"))
+			vm.Gpus = []vmmModels.Gpu{*vmmModels.NewGpu()}
+			Expect(m.getInstanceType(vm)).To(Equal("gpu"))
+		})
+
+		It("should fall back to the static instance type if the VM shape is unknown", func() { // nolint:typecheck
+			vm.MemorySizeBytes = nil
+			Expect(m.getInstanceType(vm)).To(Equal(constants.InstanceType))
+		})
+	})
+
+	Context("Test sanitizeLabelValue", func() {
+		It("should sanitize label values", func() { // nolint:typecheck
+			Expect(sanitizeLabelValue("prod")).To(Equal("prod"))
+			Expect(sanitizeLabelValue("Data Science/ML")).To(Equal("Data-Science-ML"))
+			Expect(sanitizeLabelValue("_team: a.b_")).To(Equal("team-a.b"))
+			Expect(sanitizeLabelValue("///")).To(BeEmpty())
+			Expect(sanitizeLabelValue(strings.Repeat("a", 62) + "-b")).To(Equal(strings.Repeat("a", 62)))
+		})
+	})
+
 	Context("Test generateProviderID", func() {
 		It("should fail if vmUUID is empty", func() { // nolint:typecheck
-			_, err := m.generateProviderID(ctx, "")
+			_, err := m.generateProviderID(ctx, nClient, &vmmModels.Vm{})
 			Expect(err).Should(HaveOccurred())
 		})
 
 		It("should return providerID in valid format", func() { // nolint:typecheck
 			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
 			Expect(vm).ToNot(BeNil())
-			providerID, err := m.generateProviderID(ctx, *vm.ExtId)
+			providerID, err := m.generateProviderID(ctx, nClient, vm)
 			Expect(err).ToNot(HaveOccurred())
 			Expect(providerID).To(Equal(fmt.Sprintf("nutanix://%s", *vm.ExtId)))
 		})
+
+		It("should return providerID in the qualified format", func() { // nolint:typecheck
+			m.config.ProviderIDFormat = config.QualifiedProviderIDFormat
+			vm := mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn)
+			Expect(vm).ToNot(BeNil())
+			providerID, err := m.generateProviderID(ctx, nClient, vm)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(providerID).To(Equal(fmt.Sprintf("nutanix://%s/%s/%s", mock.MockPrismCentralUUID, mock.MockClusterUUID, *vm.ExtId)))
+		})
+	})
+
+	Context("Test getNutanixProviderIDForNode", func() {
+		It("should accept provider IDs of both formats", func() { // nolint:typecheck
+			node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+			Expect(node).ToNot(BeNil())
+			vmUUID := strings.ToLower(node.Status.NodeInfo.SystemUUID)
+
+			id, err := m.getNutanixProviderIDForNode(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(providerID{vmUUID: vmUUID}))
+
+			node.Spec.ProviderID = fmt.Sprintf("nutanix://%s", vmUUID)
+			id, err = m.getNutanixProviderIDForNode(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(providerID{vmUUID: vmUUID}))
+
+			node.Spec.ProviderID = fmt.Sprintf("nutanix://%s/%s/%s", mock.MockPrismCentralUUID, mock.MockClusterUUID, vmUUID)
+			id, err = m.getNutanixProviderIDForNode(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal(providerID{prismCentralUUID: mock.MockPrismCentralUUID, clusterUUID: mock.MockClusterUUID, vmUUID: vmUUID}))
+			id, err = m.getNutanixInstanceIDForNode(ctx, node)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id.prismCentralUUID).To(Equal(mock.MockPrismCentralUUID))
+		})
 	})
 
 	Context("Test getTopologyInfoFromVM", func() {
@@ -0,0 +1,162 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+const metricsNamespace = "cloudprovider_nutanix"
+
+var (
+	prismRequests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "prism",
+			Name:           "requests_total",
+			Help:           "Number of requests to Prism Central, partitioned by method and status.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"method", "status"},
+	)
+
+	prismRequestDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "prism",
+			Name:           "request_duration_seconds",
+			Help:           "Latency of requests to Prism Central in seconds, partitioned by method.",
+			Buckets:        metrics.ExponentialBuckets(0.01, 2, 12),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"method"},
+	)
+
+	prismRequestsInFlight = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "prism",
+			Name:           "requests_in_flight",
+			Help:           "Number of requests to Prism Central in flight, partitioned by method.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"method"},
+	)
+
+	instanceOperations = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Name:           "instance_operations_total",
+			Help:           "Number of InstancesV2 operations, partitioned by operation and result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"operation", "result"},
+	)
+
+	prismCacheRequests = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "prism_cache",
+			Name:           "requests_total",
+			Help:           "Number of Prism lookups served by the Prism cache, partitioned by entity kind and hit, miss or bypass.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"kind", "result"},
+	)
+
+	prismCircuitBreakerState = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "prism_circuit_breaker",
+			Name:           "state",
+			Help:           "State of the Prism Central circuit breakers, 0 if closed, 1 if half-open and 2 if open, partitioned by Prism Central name.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"prism_central"},
+	)
+
+	configReloads = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Namespace:      metricsNamespace,
+			Subsystem:      "config",
+			Name:           "reloads_total",
+			Help:           "Number of changes of the config file, partitioned by whether they were applied or rejected as invalid.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+
+	registerMetricsOnce sync.Once
+)
+
+// registerMetrics registers the cloud provider metrics with the legacy registry, which is served on
+// the /metrics endpoint of the cloud controller manager.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		legacyregistry.MustRegister(
+			prismRequests,
+			prismRequestDuration,
+			prismRequestsInFlight,
+			instanceOperations,
+			prismCacheRequests,
+			prismCircuitBreakerState,
+			configReloads,
+		)
+	})
+}
+
+// prismRequestStatus classifies the result of a Prism request for the status label.
+func prismRequestStatus(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, interfaces.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, interfaces.ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, interfaces.ErrThrottled):
+		return "throttled"
+	case errors.Is(err, interfaces.ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+// recordInstanceOperation counts an InstancesV2 operation with the given result, or error if it failed.
+func recordInstanceOperation(operation string, result string, err error) {
+	if err != nil {
+		result = "error"
+	}
+	instanceOperations.WithLabelValues(operation, result).Inc()
+}
+
+func outcome(ok bool, whenTrue string, whenFalse string) string {
+	if ok {
+		return whenTrue
+	}
+	return whenFalse
+}
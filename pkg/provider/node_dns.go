@@ -0,0 +1,139 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+// nodeDNSTemplateData holds the VM, cluster and host fields available to the node DNS templates.
+type nodeDNSTemplateData struct {
+	VMName      string
+	VMUUID      string
+	ClusterName string
+	ClusterUUID string
+	HostName    string
+	HostUUID    string
+}
+
+// nodeDNSTemplate renders the DNS name of a node address type.
+type nodeDNSTemplate struct {
+	addressType v1.NodeAddressType
+	template    *template.Template
+}
+
+func parseNodeDNSTemplates(nodeDNS *config.NodeDNS) ([]nodeDNSTemplate, error) {
+	if nodeDNS == nil {
+		return nil, nil
+	}
+	templates := make([]nodeDNSTemplate, 0, 2)
+	for _, t := range []struct {
+		field       string
+		addressType v1.NodeAddressType
+		text        string
+	}{
+		{"nodeDNS.internalDNSTemplate", v1.NodeInternalDNS, nodeDNS.InternalDNSTemplate},
+		{"nodeDNS.externalDNSTemplate", v1.NodeExternalDNS, nodeDNS.ExternalDNSTemplate},
+	} {
+		if t.text == "" {
+			continue
+		}
+		tmpl, err := template.New(t.field).Option("missingkey=error").Parse(t.text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", t.field, err)
+		}
+		templates = append(templates, nodeDNSTemplate{addressType: t.addressType, template: tmpl})
+	}
+	return templates, nil
+}
+
+// getNodeDNSAddresses renders the configured node DNS templates for the VM. The cluster and host
+// of the VM are only looked up if DNS templates are configured.
+func (n *nutanixManager) getNodeDNSAddresses(ctx context.Context, vm *vmmModels.Vm) ([]v1.NodeAddress, error) {
+	templates := n.getNodeSettings().nodeDNSTemplates
+	if len(templates) == 0 {
+		return nil, nil
+	}
+	data, err := n.getNodeDNSTemplateData(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := make([]v1.NodeAddress, 0, len(templates))
+	for _, t := range templates {
+		var name strings.Builder
+		if err := t.template.Execute(&name, data); err != nil {
+			return nil, fmt.Errorf("failed to render %s name of VM %s: %v", t.addressType, data.VMUUID, err)
+		}
+		dnsName := strings.ToLower(strings.TrimSpace(name.String()))
+		if dnsName == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Subdomain(dnsName); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid %s name %q of VM %s: %s", t.addressType, dnsName, data.VMUUID, strings.Join(errs, ", "))
+		}
+		addresses = append(addresses, v1.NodeAddress{Type: t.addressType, Address: dnsName})
+	}
+	return addresses, nil
+}
+
+func (n *nutanixManager) getNodeDNSTemplateData(ctx context.Context, vm *vmmModels.Vm) (*nodeDNSTemplateData, error) {
+	data := &nodeDNSTemplateData{
+		VMName: ptr.Deref(vm.Name, ""),
+		VMUUID: ptr.Deref(vm.ExtId, ""),
+	}
+	if vm.Cluster == nil || vm.Cluster.ExtId == nil {
+		return data, nil
+	}
+
+	nClient, err := n.prismForVM(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := nClient.GetCluster(ctx, *vm.Cluster.ExtId)
+	if err != nil {
+		return nil, err
+	}
+	data.ClusterUUID = *vm.Cluster.ExtId
+	if cluster != nil {
+		data.ClusterName = ptr.Deref(cluster.Name, "")
+	}
+
+	// Powered off VMs are not assigned to a host
+	if vm.Host == nil || vm.Host.ExtId == nil {
+		return data, nil
+	}
+	host, err := nClient.GetClusterHost(ctx, *vm.Cluster.ExtId, *vm.Host.ExtId)
+	if err != nil {
+		return nil, err
+	}
+	data.HostUUID = *vm.Host.ExtId
+	if host != nil {
+		data.HostName = ptr.Deref(host.HostName, "")
+	}
+	return data, nil
+}
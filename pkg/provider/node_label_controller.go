@@ -0,0 +1,171 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	cloudprovider "k8s.io/cloud-provider"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+	"k8s.io/cloud-provider/app"
+	cloudcontrollerconfig "k8s.io/cloud-provider/app/config"
+	genericcontrollermanager "k8s.io/controller-manager/app"
+	"k8s.io/controller-manager/controller"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+)
+
+const (
+	// NodeLabelControllerName is the name the node label controller is enabled with in --controllers.
+	NodeLabelControllerName = "node-label-controller"
+
+	nodeMigratedReason = "NodeMigrated"
+)
+
+// nodeLabelController keeps the custom labels of initialized nodes in sync with Prism. The cloud node
+// controller only labels nodes during initialization, so placement labels would go stale after a
+// live migration of the VM to another host.
+type nodeLabelController struct {
+	manager     *nutanixManager
+	nodeLister  corelisters.NodeLister
+	nodesSynced cache.InformerSynced
+	recorder    record.EventRecorder
+	period      time.Duration
+}
+
+// StartNodeLabelControllerWrapper returns the InitFunc of the node label controller. The controller
+// reconciles the labels of all nodes every node status update period. It is started even if custom
+// labeling is disabled, as labeling can be enabled by reloading the config.
+func StartNodeLabelControllerWrapper(initContext app.ControllerInitContext, completedConfig *cloudcontrollerconfig.CompletedConfig, cloud cloudprovider.Interface) app.InitFunc {
+	return func(ctx context.Context, _ genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		ntnxCloud, ok := cloud.(*NtnxCloud)
+		if !ok {
+			return nil, false, fmt.Errorf("%s requires the %s cloud provider", NodeLabelControllerName, constants.ProviderName)
+		}
+		client := completedConfig.ClientBuilder.ClientOrDie(initContext.ClientName)
+		c := newNodeLabelController(
+			ntnxCloud.manager,
+			completedConfig.SharedInformers.Core().V1().Nodes(),
+			newNodeLabelEventRecorder(ctx, client),
+			completedConfig.ComponentConfig.NodeStatusUpdateFrequency.Duration,
+		)
+		go c.Run(ctx)
+		return nil, true, nil
+	}
+}
+
+func newNodeLabelController(manager *nutanixManager, nodeInformer coreinformers.NodeInformer, recorder record.EventRecorder, period time.Duration) *nodeLabelController {
+	return &nodeLabelController{
+		manager:     manager,
+		nodeLister:  nodeInformer.Lister(),
+		nodesSynced: nodeInformer.Informer().HasSynced,
+		recorder:    recorder,
+		period:      period,
+	}
+}
+
+func newNodeLabelEventRecorder(ctx context.Context, client clientset.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster(record.WithContext(ctx))
+	broadcaster.StartStructuredLogging(0)
+	broadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: NodeLabelControllerName})
+}
+
+// Run reconciles the node labels until the context is cancelled.
+func (c *nodeLabelController) Run(ctx context.Context) {
+	defer utilruntime.HandleCrash()
+
+	klog.Infof("starting %s", NodeLabelControllerName) //nolint:typecheck
+	if !cache.WaitForNamedCacheSync(NodeLabelControllerName, ctx.Done(), c.nodesSynced) {
+		return
+	}
+	wait.UntilWithContext(ctx, c.reconcileNodes, c.period)
+}
+
+func (c *nodeLabelController) reconcileNodes(ctx context.Context) {
+	nodes, err := c.nodeLister.List(labels.Everything())
+	if err != nil {
+		klog.Errorf("failed to list nodes: %v", err) //nolint:typecheck
+		return
+	}
+	for _, node := range nodes {
+		if err := c.reconcileNode(ctx, node); err != nil {
+			klog.Errorf("failed to reconcile labels of node %s: %v", node.Name, err) //nolint:typecheck
+		}
+	}
+}
+
+// reconcileNode updates the custom labels of an initialized node, and records an Event if the VM of
+// the node moved to another host or cluster. Whether labeling is enabled is read from the current
+// config.
+func (c *nodeLabelController) reconcileNode(ctx context.Context, node *v1.Node) error {
+	if !isInitializedNutanixNode(node) || !c.manager.isCustomLabelingEnabled(node) {
+		return nil
+	}
+	nodeLabels, err := c.manager.getCustomLabels(ctx, node)
+	if err != nil {
+		return err
+	}
+	c.recordMigration(node, nodeLabels)
+	return c.manager.updateManagedNodeLabels(ctx, node.Name, nodeLabels)
+}
+
+func (c *nodeLabelController) recordMigration(node *v1.Node, nodeLabels map[string]string) {
+	for _, placement := range []struct {
+		label  string
+		entity string
+	}{
+		{constants.CustomPENameLabel, "cluster"},
+		{constants.CustomHostNameLabel, "host"},
+	} {
+		previous, current := node.Labels[placement.label], nodeLabels[placement.label]
+		if previous == "" || current == "" || previous == current {
+			continue
+		}
+		klog.Infof("VM of node %s migrated from %s %s to %s", node.Name, placement.entity, previous, current) //nolint:typecheck
+		c.recorder.Eventf(node, v1.EventTypeNormal, nodeMigratedReason, "VM migrated from %s %s to %s", placement.entity, previous, current)
+	}
+}
+
+// isInitializedNutanixNode returns true if the cloud node controller has initialized the node.
+// Labels of uninitialized nodes are set during initialization.
+func isInitializedNutanixNode(node *v1.Node) bool {
+	if !strings.HasPrefix(node.Spec.ProviderID, constants.ProviderName+"://") {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == cloudproviderapi.TaintExternalCloudProvider {
+			return false
+		}
+	}
+	return true
+}
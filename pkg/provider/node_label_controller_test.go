@@ -0,0 +1,146 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+	cloudproviderapi "k8s.io/cloud-provider/api"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test NodeLabelController", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		kClient         *fake.Clientset
+		mockEnvironment *mock.MockEnvironment
+		recorder        *record.FakeRecorder
+		c               *nodeLabelController
+	)
+
+	getNode := func(name string) *v1.Node {
+		node, err := kClient.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		return node
+	}
+
+	initializeNode := func(name string) *v1.Node {
+		node := getNode(name)
+		node.Spec.ProviderID = fmt.Sprintf("%s://%s", constants.ProviderName, node.Status.NodeInfo.SystemUUID)
+		node, err := kClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.manager.addCustomLabelsToNode(ctx, node)).To(Succeed())
+		return getNode(name)
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		kClient = fake.NewSimpleClientset()
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+		manager, err := newNutanixManager(config.Config{EnableCustomLabeling: true})
+		Expect(err).ToNot(HaveOccurred())
+		manager.client = kClient
+		manager.nutanixClient = mock.CreateMockClient(*mockEnvironment)
+		recorder = record.NewFakeRecorder(10)
+		c = newNodeLabelController(manager, informers.NewSharedInformerFactory(kClient, 0).Core().V1().Nodes(), recorder, 0)
+	})
+
+	It("should update host labels and record an event after a migration", func() {
+		node := initializeNode(mock.MockVMNamePoweredOn)
+		Expect(node.Labels).To(HaveKeyWithValue(constants.CustomHostUUIDLabel, mock.MockHostUUID))
+
+		migratedHostUUID := "00000000-0000-0000-0000-000000000011"
+		mockEnvironment.AddHost(mock.CreateHost("mock-migrated-host", migratedHostUUID, mock.MockClusterUUID))
+		mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn).Host = &vmmModels.HostReference{ExtId: ptr.To(migratedHostUUID)}
+
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		node = getNode(mock.MockVMNamePoweredOn)
+		Expect(node.Labels).To(HaveKeyWithValue(constants.CustomHostUUIDLabel, migratedHostUUID))
+		Expect(node.Labels).To(HaveKeyWithValue(constants.CustomHostNameLabel, "mock-migrated-host"))
+		Expect(recorder.Events).To(Receive(And(ContainSubstring(nodeMigratedReason), ContainSubstring("mock-migrated-host"))))
+	})
+
+	It("should remove host labels when the VM is powered off", func() {
+		node := initializeNode(mock.MockVMNamePoweredOn)
+		mockEnvironment.GetVM(ctx, mock.MockVMNamePoweredOn).Host = nil
+
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		node = getNode(mock.MockVMNamePoweredOn)
+		Expect(node.Labels).ToNot(HaveKey(constants.CustomHostUUIDLabel))
+		Expect(node.Labels).To(HaveKey(constants.CustomPEUUIDLabel))
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("should not record an event if the placement did not change", func() {
+		node := initializeNode(mock.MockVMNamePoweredOn)
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNode(mock.MockVMNamePoweredOn).Labels).To(Equal(node.Labels))
+		Expect(recorder.Events).ToNot(Receive())
+	})
+
+	It("should follow custom labeling changes of the reloaded config", func() {
+		c.manager.config.EnableCustomLabeling = false
+		node := getNode(mock.MockVMNamePoweredOn)
+		node.Spec.ProviderID = fmt.Sprintf("%s://%s", constants.ProviderName, node.Status.NodeInfo.SystemUUID)
+		node, err := kClient.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNode(mock.MockVMNamePoweredOn).Labels).To(BeEmpty())
+
+		c.manager.config.EnableCustomLabeling = true
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		node = getNode(mock.MockVMNamePoweredOn)
+		Expect(node.Labels).To(HaveKeyWithValue(constants.CustomHostUUIDLabel, mock.MockHostUUID))
+
+		c.manager.config.EnableCustomLabeling = false
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNode(mock.MockVMNamePoweredOn).Labels).To(BeEmpty())
+	})
+
+	It("should skip uninitialized nodes", func() {
+		node := getNode(mock.MockVMNamePoweredOn)
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNode(mock.MockVMNamePoweredOn).Labels).To(BeEmpty())
+
+		node.Spec.ProviderID = fmt.Sprintf("%s://%s", constants.ProviderName, node.Status.NodeInfo.SystemUUID)
+		node.Spec.Taints = []v1.Taint{{Key: cloudproviderapi.TaintExternalCloudProvider, Effect: v1.TaintEffectNoSchedule}}
+		Expect(c.reconcileNode(ctx, node)).To(Succeed())
+		Expect(getNode(mock.MockVMNamePoweredOn).Labels).To(BeEmpty())
+	})
+
+	It("should fail if the VM of the node does not exist", func() {
+		node := mockEnvironment.GetNode(mock.MockNodeNameVMNotExisting)
+		node.Spec.ProviderID = fmt.Sprintf("%s://%s", constants.ProviderName, mock.MockNodeNameVMNotExisting)
+		Expect(c.reconcileNode(ctx, node)).ToNot(Succeed())
+	})
+})
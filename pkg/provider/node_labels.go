@@ -0,0 +1,98 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+)
+
+// updateManagedNodeLabels sets the labels on the node and removes the labels of a previous update
+// which are no longer computed. The keys of the labels owned by the cloud provider are tracked in
+// the managed labels annotation, so labels set by other components are never removed.
+func (n *nutanixManager) updateManagedNodeLabels(ctx context.Context, nodeName string, labels map[string]string) error {
+	node, err := n.client.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+
+	patchLabels := map[string]*string{}
+	for key, value := range labels {
+		if current, ok := node.Labels[key]; !ok || current != value {
+			patchLabels[key] = ptr.To(value)
+		}
+	}
+	for _, key := range managedNodeLabelKeys(node) {
+		if _, ok := labels[key]; ok {
+			continue
+		}
+		if _, ok := node.Labels[key]; ok {
+			patchLabels[key] = nil
+		}
+	}
+
+	managedLabels := strings.Join(slices.Sorted(maps.Keys(labels)), ",")
+	annotations := map[string]*string{}
+	if managedLabels != node.Annotations[constants.ManagedLabelsAnnotation] {
+		annotations[constants.ManagedLabelsAnnotation] = ptr.To(managedLabels)
+		if managedLabels == "" {
+			annotations[constants.ManagedLabelsAnnotation] = nil
+		}
+	}
+	if len(patchLabels) == 0 && len(annotations) == 0 {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"labels":      patchLabels,
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal labels patch of node %s: %w", nodeName, err)
+	}
+	klog.V(1).Infof("updating labels of node %s: %s", nodeName, patch) //nolint:typecheck
+	if _, err := n.client.CoreV1().Nodes().Patch(ctx, nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to update labels of node %s: %w", nodeName, err)
+	}
+	return nil
+}
+
+func hasManagedNodeLabels(node *v1.Node) bool {
+	return len(managedNodeLabelKeys(node)) > 0
+}
+
+func managedNodeLabelKeys(node *v1.Node) []string {
+	value := node.Annotations[constants.ManagedLabelsAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
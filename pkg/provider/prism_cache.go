@@ -0,0 +1,206 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"golang.org/x/sync/singleflight"
+	"k8s.io/apimachinery/pkg/util/cache"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+const (
+	defaultPrismCacheMaxEntries  = 10000
+	defaultPrismCacheVMTTL       = 30 * time.Second
+	defaultPrismCacheClusterTTL  = 5 * time.Minute
+	defaultPrismCacheHostTTL     = time.Minute
+	defaultPrismCacheCategoryTTL = 10 * time.Minute
+	defaultPrismCacheSubnetTTL   = 10 * time.Minute
+	defaultPrismCacheNotFoundTTL = 30 * time.Second
+)
+
+const (
+	vmCacheKind         = "vm"
+	vmsCacheKind        = "vms"
+	clusterCacheKind    = "cluster"
+	clustersCacheKind   = "clusters"
+	hostCacheKind       = "host"
+	categoryCacheKind   = "category"
+	categoriesCacheKind = "categories"
+	subnetCacheKind     = "subnet"
+)
+
+// prismCache holds Prism entities by kind and UUID. Entities expire after the TTL of their kind,
+// and the least recently used entities are evicted once the cache is full. Concurrent lookups of the
+// same entity share a single request.
+type prismCache struct {
+	entries     *cache.LRUExpireCache
+	ttls        map[string]time.Duration
+	notFoundTTL time.Duration
+	lookups     singleflight.Group
+}
+
+type prismCacheBypassKey struct{}
+
+// withoutPrismCache returns a context whose Prism lookups are sent to Prism even if they are cached.
+// It is used where a stale answer is harmful, e.g. deciding whether a node is deleted or shut down.
+// The results still refresh the cache.
+func withoutPrismCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, prismCacheBypassKey{}, true)
+}
+
+type prismCacheEntry struct {
+	value any
+	err   error
+}
+
+// newPrismCache returns nil if the cache is disabled.
+func newPrismCache(cacheConfig config.PrismCache) *prismCache {
+	if cacheConfig.Disabled {
+		return nil
+	}
+	maxEntries := cacheConfig.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultPrismCacheMaxEntries
+	}
+	vmTTL := durationOrDefault(cacheConfig.VMTTL.Duration, defaultPrismCacheVMTTL)
+	clusterTTL := durationOrDefault(cacheConfig.ClusterTTL.Duration, defaultPrismCacheClusterTTL)
+	categoryTTL := durationOrDefault(cacheConfig.CategoryTTL.Duration, defaultPrismCacheCategoryTTL)
+	return &prismCache{
+		entries: cache.NewLRUExpireCache(maxEntries),
+		ttls: map[string]time.Duration{
+			vmCacheKind:         vmTTL,
+			vmsCacheKind:        vmTTL,
+			clusterCacheKind:    clusterTTL,
+			clustersCacheKind:   clusterTTL,
+			hostCacheKind:       durationOrDefault(cacheConfig.HostTTL.Duration, defaultPrismCacheHostTTL),
+			categoryCacheKind:   categoryTTL,
+			categoriesCacheKind: categoryTTL,
+			subnetCacheKind:     durationOrDefault(cacheConfig.SubnetTTL.Duration, defaultPrismCacheSubnetTTL),
+		},
+		notFoundTTL: durationOrDefault(cacheConfig.NotFoundTTL.Duration, defaultPrismCacheNotFoundTTL),
+	}
+}
+
+func durationOrDefault(d time.Duration, defaultDuration time.Duration) time.Duration {
+	if d == 0 {
+		return defaultDuration
+	}
+	return d
+}
+
+// getCached returns the cached entity or looks it up with get, unless the context bypasses the cache.
+// Successful lookups are cached with the TTL of the kind, and lookups of entities which do not exist
+// with the not found TTL.
+func getCached[T any](ctx context.Context, c *prismCache, kind string, key string, get func() (T, error)) (T, error) {
+	cacheKey := kind + "/" + key
+	if bypass, _ := ctx.Value(prismCacheBypassKey{}).(bool); bypass {
+		prismCacheRequests.WithLabelValues(kind, "bypass").Inc()
+	} else if cached, ok := c.entries.Get(cacheKey); ok {
+		prismCacheRequests.WithLabelValues(kind, "hit").Inc()
+		entry := cached.(prismCacheEntry)
+		value, _ := entry.value.(T)
+		return value, entry.err
+	} else {
+		prismCacheRequests.WithLabelValues(kind, "miss").Inc()
+	}
+
+	value, err, _ := c.lookups.Do(cacheKey, func() (any, error) {
+		value, err := get()
+		switch {
+		case err == nil:
+			c.entries.Add(cacheKey, prismCacheEntry{value: value}, c.ttls[kind])
+		case errors.Is(err, interfaces.ErrNotFound):
+			c.entries.Add(cacheKey, prismCacheEntry{err: err}, c.notFoundTTL)
+		}
+		return value, err
+	})
+	typed, _ := value.(T)
+	return typed, err
+}
+
+// cachedPrism is a read-through cache for the Prism lookups done for every node. The returned
+// entities are shared between callers and must not be modified. Other calls are passed through.
+type cachedPrism struct {
+	interfaces.Prism
+	cache *prismCache
+}
+
+func newCachedPrism(prism interfaces.Prism, c *prismCache) interfaces.Prism {
+	return &cachedPrism{Prism: prism, cache: c}
+}
+
+func (p *cachedPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	return getCached(ctx, p.cache, vmCacheKind, vmUUID, func() (*vmmModels.Vm, error) {
+		return p.Prism.GetVM(ctx, vmUUID)
+	})
+}
+
+// ListVMs caches the VMs by filter, as the fallback VM lookups may list every VM of Prism Central
+// for each node which is initialized.
+func (p *cachedPrism) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	return getCached(ctx, p.cache, vmsCacheKind, filter, func() ([]vmmModels.Vm, error) {
+		return p.Prism.ListVMs(ctx, filter)
+	})
+}
+
+func (p *cachedPrism) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
+	return getCached(ctx, p.cache, clusterCacheKind, clusterUUID, func() (*clusterModels.Cluster, error) {
+		return p.Prism.GetCluster(ctx, clusterUUID)
+	})
+}
+
+func (p *cachedPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+	return getCached(ctx, p.cache, clustersCacheKind, "", func() ([]clusterModels.Cluster, error) {
+		return p.Prism.ListAllCluster(ctx)
+	})
+}
+
+func (p *cachedPrism) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
+	return getCached(ctx, p.cache, categoryCacheKind, categoryUUID, func() (*prismModels.Category, error) {
+		return p.Prism.GetCategory(ctx, categoryUUID)
+	})
+}
+
+// ListCategories caches the categories by filter, as the expanded associations make the response
+// large and it is listed for every node.
+func (p *cachedPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	return getCached(ctx, p.cache, categoriesCacheKind, filter, func() ([]prismModels.Category, error) {
+		return p.Prism.ListCategories(ctx, filter)
+	})
+}
+
+func (p *cachedPrism) GetClusterHost(ctx context.Context, clusterUUID string, hostUUID string) (*clusterModels.Host, error) {
+	return getCached(ctx, p.cache, hostCacheKind, clusterUUID+"/"+hostUUID, func() (*clusterModels.Host, error) {
+		return p.Prism.GetClusterHost(ctx, clusterUUID, hostUUID)
+	})
+}
+
+func (p *cachedPrism) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	return getCached(ctx, p.cache, subnetCacheKind, subnetUUID, func() (*networkingModels.Subnet, error) {
+		return p.Prism.GetSubnet(ctx, subnetUUID)
+	})
+}
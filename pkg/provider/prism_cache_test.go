@@ -0,0 +1,204 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// countingPrism counts the lookups passed through to the mock Prism.
+type countingPrism struct {
+	interfaces.Prism
+	vmLookups       map[string]int
+	vmListLookups   map[string]int
+	clusterLookups  int
+	categoryLookups map[string]int
+}
+
+func (p *countingPrism) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	p.vmListLookups[filter]++
+	return p.Prism.ListVMs(ctx, filter)
+}
+
+func (p *countingPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	p.vmLookups[vmUUID]++
+	return p.Prism.GetVM(ctx, vmUUID)
+}
+
+func (p *countingPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+	p.clusterLookups++
+	return p.Prism.ListAllCluster(ctx)
+}
+
+func (p *countingPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	p.categoryLookups[filter]++
+	return p.Prism.ListCategories(ctx, filter)
+}
+
+// blockingPrism blocks VM lookups until released and counts them.
+type blockingPrism struct {
+	interfaces.Prism
+	release   chan struct{}
+	vmLookups atomic.Int32
+}
+
+func (p *blockingPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	p.vmLookups.Add(1)
+	<-p.release
+	return p.Prism.GetVM(ctx, vmUUID)
+}
+
+var _ = Describe("Test Prism cache", func() { //nolint:typecheck
+	const missingVMUUID = "00000000-0000-0000-0000-000000000999"
+
+	var (
+		ctx      context.Context
+		counting *countingPrism
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mockEnvironment, err := mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+		Expect(err).ToNot(HaveOccurred())
+		mockPrism, err := mock.CreateMockClient(*mockEnvironment).Get()
+		Expect(err).ToNot(HaveOccurred())
+		counting = &countingPrism{Prism: mockPrism, vmLookups: map[string]int{}, vmListLookups: map[string]int{}, categoryLookups: map[string]int{}}
+	})
+
+	It("should serve repeated lookups from the cache", func() {
+		prism := newCachedPrism(counting, newPrismCache(config.PrismCache{}))
+		for range 3 {
+			vm, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*vm.ExtId).To(Equal(mock.MockVMPoweredOnUUID))
+			_, err = prism.ListAllCluster(ctx)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(counting.vmLookups[mock.MockVMPoweredOnUUID]).To(Equal(1))
+		Expect(counting.clusterLookups).To(Equal(1))
+
+		_, err := prism.GetVM(ctx, mock.MockVMPoweredOffUUID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(counting.vmLookups[mock.MockVMPoweredOffUUID]).To(Equal(1))
+	})
+
+	It("should cache category lists by filter", func() {
+		prism := newCachedPrism(counting, newPrismCache(config.PrismCache{}))
+		for _, filter := range []string{"key eq 'zone'", "key eq 'zone'", "key eq 'region'"} {
+			categories, err := prism.ListCategories(ctx, filter)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(categories).ToNot(BeEmpty())
+		}
+		Expect(counting.categoryLookups).To(Equal(map[string]int{"key eq 'zone'": 1, "key eq 'region'": 1}))
+	})
+
+	It("should cache VM lists by filter", func() {
+		prism := newCachedPrism(counting, newPrismCache(config.PrismCache{}))
+		for _, filter := range []string{"", "", "startswith(name, 'k8s-')"} {
+			vms, err := prism.ListVMs(ctx, filter)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vms).ToNot(BeEmpty())
+		}
+		Expect(counting.vmListLookups).To(Equal(map[string]int{"": 1, "startswith(name, 'k8s-')": 1}))
+	})
+
+	It("should cache lookups of VMs which do not exist", func() {
+		prism := newCachedPrism(counting, newPrismCache(config.PrismCache{}))
+		for range 2 {
+			_, err := prism.GetVM(ctx, missingVMUUID)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, interfaces.ErrNotFound)).To(BeTrue())
+		}
+		Expect(counting.vmLookups[missingVMUUID]).To(Equal(1))
+	})
+
+	It("should bypass the cache but refresh it if requested", func() {
+		prism := newCachedPrism(counting, newPrismCache(config.PrismCache{}))
+		for _, vmUUID := range []string{mock.MockVMPoweredOnUUID, missingVMUUID} {
+			_, _ = prism.GetVM(ctx, vmUUID)
+			_, _ = prism.GetVM(withoutPrismCache(ctx), vmUUID)
+			Expect(counting.vmLookups[vmUUID]).To(Equal(2), "vm: %s", vmUUID)
+			_, _ = prism.GetVM(ctx, vmUUID)
+			Expect(counting.vmLookups[vmUUID]).To(Equal(2), "vm: %s", vmUUID)
+		}
+	})
+
+	It("should share concurrent lookups of the same entity", func() {
+		blocking := &blockingPrism{Prism: counting.Prism, release: make(chan struct{})}
+		prism := newCachedPrism(blocking, newPrismCache(config.PrismCache{}))
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				vm, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(*vm.ExtId).To(Equal(mock.MockVMPoweredOnUUID))
+			}()
+		}
+		Eventually(blocking.vmLookups.Load).Should(BeEquivalentTo(1))
+		// Give the other lookups time to join the pending one
+		time.Sleep(50 * time.Millisecond)
+		close(blocking.release)
+		wg.Wait()
+		Expect(blocking.vmLookups.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should look up entities again once they expire", func() {
+		prism := newCachedPrism(counting, newPrismCache(config.PrismCache{
+			VMTTL:       metav1.Duration{Duration: time.Millisecond},
+			NotFoundTTL: metav1.Duration{Duration: time.Millisecond},
+		}))
+		for _, vmUUID := range []string{mock.MockVMPoweredOnUUID, missingVMUUID} {
+			_, _ = prism.GetVM(ctx, vmUUID)
+			time.Sleep(5 * time.Millisecond)
+			_, _ = prism.GetVM(ctx, vmUUID)
+			Expect(counting.vmLookups[vmUUID]).To(Equal(2), "vm: %s", vmUUID)
+		}
+	})
+
+	It("should evict entities once the cache is full", func() {
+		prism := newCachedPrism(counting, newPrismCache(config.PrismCache{MaxEntries: 1}))
+		for _, vmUUID := range []string{mock.MockVMPoweredOnUUID, mock.MockVMPoweredOffUUID, mock.MockVMPoweredOnUUID} {
+			_, err := prism.GetVM(ctx, vmUUID)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(counting.vmLookups[mock.MockVMPoweredOnUUID]).To(Equal(2))
+	})
+
+	It("should not create a cache if disabled", func() {
+		Expect(newPrismCache(config.PrismCache{Disabled: true})).To(BeNil())
+	})
+})
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	clusterClient "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/client"
+	networkingClient "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/client"
+	prismClient "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/client"
+	vmmClient "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/client"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// v4ErrorResponse is the part of the v4 API error payload used to classify errors.
+type v4ErrorResponse struct {
+	Data struct {
+		Error []struct {
+			ErrorGroup string `json:"errorGroup"`
+		} `json:"error"`
+	} `json:"data"`
+}
+
+// prismResult maps the error of a Prism API call with toPrismError.
+func prismResult[T any](value T, err error) (T, error) {
+	return value, toPrismError(err)
+}
+
+// toPrismError wraps the error of a Prism API call with the matching error of the interfaces
+// package. Errors which do not match any of them are returned unchanged.
+func toPrismError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if sentinel := classifyPrismError(err); sentinel != nil {
+		return fmt.Errorf("%w: %w", sentinel, err)
+	}
+	return err
+}
+
+func classifyPrismError(err error) error {
+	if status, body, ok := openAPIErrorResponse(err); ok {
+		switch status {
+		case http.StatusNotFound:
+			return interfaces.ErrNotFound
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return interfaces.ErrUnauthorized
+		case http.StatusTooManyRequests:
+			return interfaces.ErrThrottled
+		}
+		// Older Prism Central releases report some missing entities with other status codes
+		if isNotFoundErrorGroup(body) {
+			return interfaces.ErrNotFound
+		}
+		switch status {
+		// Prism Central reports transient failures of its backing services as internal errors
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return interfaces.ErrUnavailable
+		}
+		return nil
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return interfaces.ErrUnavailable
+	}
+	return nil
+}
+
+// openAPIErrorResponse returns the HTTP status code and body of an error response of the v4 SDKs.
+// Every SDK defines its own error type.
+func openAPIErrorResponse(err error) (int, []byte, bool) {
+	var status string
+	var body []byte
+	var vmmErr vmmClient.GenericOpenAPIError
+	var clusterErr clusterClient.GenericOpenAPIError
+	var prismErr prismClient.GenericOpenAPIError
+	var networkingErr networkingClient.GenericOpenAPIError
+	switch {
+	case errors.As(err, &vmmErr):
+		status, body = vmmErr.Status, vmmErr.Body
+	case errors.As(err, &clusterErr):
+		status, body = clusterErr.Status, clusterErr.Body
+	case errors.As(err, &prismErr):
+		status, body = prismErr.Status, prismErr.Body
+	case errors.As(err, &networkingErr):
+		status, body = networkingErr.Status, networkingErr.Body
+	default:
+		return 0, nil, false
+	}
+
+	code, _, _ := strings.Cut(status, " ")
+	statusCode, err := strconv.Atoi(code)
+	if err != nil {
+		statusCode = 0
+	}
+	return statusCode, body, true
+}
+
+func isNotFoundErrorGroup(body []byte) bool {
+	var response v4ErrorResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return false
+	}
+	for _, e := range response.Data.Error {
+		if strings.HasSuffix(e.ErrorGroup, "_NOT_FOUND") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+
+	clusterClient "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/client"
+	networkingClient "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/client"
+	vmmClient "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/client"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+func TestToPrismError(t *testing.T) {
+	vmNotFoundBody := []byte(`{"data":{"error":[{"code":"VMM-20005","errorGroup":"VM_NOT_FOUND"}],"$objectType":"vmm.v4.error.ErrorResponse"}}`)
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "no error",
+			err:  nil,
+			want: nil,
+		},
+		{
+			name: "not found status",
+			err:  fmt.Errorf("failed to get vm: %w", vmmClient.GenericOpenAPIError{Status: "404 Not Found"}),
+			want: interfaces.ErrNotFound,
+		},
+		{
+			name: "not found error group",
+			err:  fmt.Errorf("failed to get vm: %w", vmmClient.GenericOpenAPIError{Status: "500 Internal Server Error", Body: vmNotFoundBody}),
+			want: interfaces.ErrNotFound,
+		},
+		{
+			name: "unauthorized",
+			err:  clusterClient.GenericOpenAPIError{Status: "401 Unauthorized"},
+			want: interfaces.ErrUnauthorized,
+		},
+		{
+			name: "forbidden",
+			err:  clusterClient.GenericOpenAPIError{Status: "403 Forbidden"},
+			want: interfaces.ErrUnauthorized,
+		},
+		{
+			name: "throttled",
+			err:  networkingClient.GenericOpenAPIError{Status: "429 Too Many Requests"},
+			want: interfaces.ErrThrottled,
+		},
+		{
+			name: "service unavailable",
+			err:  networkingClient.GenericOpenAPIError{Status: "503 Service Unavailable"},
+			want: interfaces.ErrUnavailable,
+		},
+		{
+			name: "internal server error",
+			err:  vmmClient.GenericOpenAPIError{Status: "500 Internal Server Error"},
+			want: interfaces.ErrUnavailable,
+		},
+		{
+			name: "connection refused",
+			err:  &url.Error{Op: "Get", URL: "https://prism:9440", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}},
+			want: interfaces.ErrUnavailable,
+		},
+		{
+			name: "other API error",
+			err:  vmmClient.GenericOpenAPIError{Status: "400 Bad Request"},
+			want: nil,
+		},
+		{
+			name: "canceled request",
+			err:  &url.Error{Op: "Get", URL: "https://prism:9440", Err: context.Canceled},
+			want: nil,
+		},
+		{
+			name: "other error",
+			err:  errors.New("VM_NOT_FOUND"),
+			want: nil,
+		},
+	}
+	sentinels := []error{interfaces.ErrNotFound, interfaces.ErrUnauthorized, interfaces.ErrThrottled, interfaces.ErrUnavailable}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := toPrismError(tt.err)
+			if (got == nil) != (tt.err == nil) {
+				t.Fatalf("toPrismError() = %v, want error %v", got, tt.err)
+			}
+			if tt.err != nil && !strings.Contains(got.Error(), tt.err.Error()) {
+				t.Errorf("toPrismError() = %v, does not wrap %v", got, tt.err)
+			}
+			for _, sentinel := range sentinels {
+				if is := errors.Is(got, sentinel); is != (sentinel == tt.want) {
+					t.Errorf("errors.Is(toPrismError(), %v) = %v, want %v", sentinel, is, !is)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// instrumentedPrism records the count, latency and concurrency of the requests sent to Prism
+// Central, and traces each of them as a span. It wraps the client directly, so every retry is
+// recorded as a request of its own.
+type instrumentedPrism struct {
+	prism  interfaces.Prism
+	tracer trace.Tracer
+}
+
+// newInstrumentedPrism does not trace requests if tracer is nil.
+func newInstrumentedPrism(prism interfaces.Prism, tracer trace.Tracer) interfaces.Prism {
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer(tracerName)
+	}
+	return &instrumentedPrism{prism: prism, tracer: tracer}
+}
+
+func (p *instrumentedPrism) observe(ctx context.Context, method string, attributes []attribute.KeyValue, f func(context.Context) error) error {
+	ctx, span := p.tracer.Start(ctx, "Prism."+method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+	inFlight := prismRequestsInFlight.WithLabelValues(method)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	err := f(ctx)
+	prismRequestDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	status := prismRequestStatus(err)
+	prismRequests.WithLabelValues(method, status).Inc()
+	endSpan(span, status, err)
+	return err
+}
+
+func observePrismRequestValue[T any](ctx context.Context, p *instrumentedPrism, method string, attributes []attribute.KeyValue, f func(context.Context) (T, error)) (T, error) {
+	var value T
+	err := p.observe(ctx, method, attributes, func(ctx context.Context) error {
+		var err error
+		value, err = f(ctx)
+		return err
+	})
+	return value, err
+}
+
+func (p *instrumentedPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	attributes := []attribute.KeyValue{vmUUIDAttribute.String(vmUUID)}
+	return observePrismRequestValue(ctx, p, "GetVM", attributes, func(ctx context.Context) (*vmmModels.Vm, error) {
+		vm, err := p.prism.GetVM(ctx, vmUUID)
+		if err == nil && vm != nil && vm.Cluster != nil && vm.Cluster.ExtId != nil {
+			trace.SpanFromContext(ctx).SetAttributes(clusterUUIDAttribute.String(*vm.Cluster.ExtId))
+		}
+		return vm, err
+	})
+}
+
+func (p *instrumentedPrism) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	return observePrismRequestValue(ctx, p, "ListVMs", nil, func(ctx context.Context) ([]vmmModels.Vm, error) {
+		return p.prism.ListVMs(ctx, filter)
+	})
+}
+
+func (p *instrumentedPrism) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
+	attributes := []attribute.KeyValue{clusterUUIDAttribute.String(clusterUUID)}
+	return observePrismRequestValue(ctx, p, "GetCluster", attributes, func(ctx context.Context) (*clusterModels.Cluster, error) {
+		return p.prism.GetCluster(ctx, clusterUUID)
+	})
+}
+
+func (p *instrumentedPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+	return observePrismRequestValue(ctx, p, "ListAllCluster", nil, func(ctx context.Context) ([]clusterModels.Cluster, error) {
+		return p.prism.ListAllCluster(ctx)
+	})
+}
+
+func (p *instrumentedPrism) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
+	attributes := []attribute.KeyValue{categoryUUIDAttribute.String(categoryUUID)}
+	return observePrismRequestValue(ctx, p, "GetCategory", attributes, func(ctx context.Context) (*prismModels.Category, error) {
+		return p.prism.GetCategory(ctx, categoryUUID)
+	})
+}
+
+func (p *instrumentedPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	return observePrismRequestValue(ctx, p, "ListCategories", nil, func(ctx context.Context) ([]prismModels.Category, error) {
+		return p.prism.ListCategories(ctx, filter)
+	})
+}
+
+func (p *instrumentedPrism) GetClusterHost(ctx context.Context, clusterUUID string, hostUUID string) (*clusterModels.Host, error) {
+	attributes := []attribute.KeyValue{clusterUUIDAttribute.String(clusterUUID), hostUUIDAttribute.String(hostUUID)}
+	return observePrismRequestValue(ctx, p, "GetClusterHost", attributes, func(ctx context.Context) (*clusterModels.Host, error) {
+		return p.prism.GetClusterHost(ctx, clusterUUID, hostUUID)
+	})
+}
+
+func (p *instrumentedPrism) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	attributes := []attribute.KeyValue{subnetUUIDAttribute.String(subnetUUID)}
+	return observePrismRequestValue(ctx, p, "GetSubnet", attributes, func(ctx context.Context) (*networkingModels.Subnet, error) {
+		return p.prism.GetSubnet(ctx, subnetUUID)
+	})
+}
+
+func (p *instrumentedPrism) ListLoadBalancerSessions(ctx context.Context, filter string) ([]networkingModels.LoadBalancerSession, error) {
+	return observePrismRequestValue(ctx, p, "ListLoadBalancerSessions", nil, func(ctx context.Context) ([]networkingModels.LoadBalancerSession, error) {
+		return p.prism.ListLoadBalancerSessions(ctx, filter)
+	})
+}
+
+func (p *instrumentedPrism) CreateLoadBalancerSession(ctx context.Context, session *networkingModels.LoadBalancerSession) error {
+	return p.observe(ctx, "CreateLoadBalancerSession", nil, func(ctx context.Context) error {
+		return p.prism.CreateLoadBalancerSession(ctx, session)
+	})
+}
+
+func (p *instrumentedPrism) UpdateLoadBalancerSession(ctx context.Context, sessionUUID string, session *networkingModels.LoadBalancerSession) error {
+	attributes := []attribute.KeyValue{loadBalancerSessionAttribute.String(sessionUUID)}
+	return p.observe(ctx, "UpdateLoadBalancerSession", attributes, func(ctx context.Context) error {
+		return p.prism.UpdateLoadBalancerSession(ctx, sessionUUID, session)
+	})
+}
+
+func (p *instrumentedPrism) DeleteLoadBalancerSession(ctx context.Context, sessionUUID string) error {
+	attributes := []attribute.KeyValue{loadBalancerSessionAttribute.String(sessionUUID)}
+	return p.observe(ctx, "DeleteLoadBalancerSession", attributes, func(ctx context.Context) error {
+		return p.prism.DeleteLoadBalancerSession(ctx, sessionUUID)
+	})
+}
+
+func (p *instrumentedPrism) ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error) {
+	return observePrismRequestValue(ctx, p, "ListRouteTables", nil, func(ctx context.Context) ([]networkingModels.RouteTable, error) {
+		return p.prism.ListRouteTables(ctx, filter)
+	})
+}
+
+func (p *instrumentedPrism) ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error) {
+	attributes := []attribute.KeyValue{routeTableUUIDAttribute.String(routeTableUUID)}
+	return observePrismRequestValue(ctx, p, "ListRoutes", attributes, func(ctx context.Context) ([]networkingModels.Route, error) {
+		return p.prism.ListRoutes(ctx, routeTableUUID, filter)
+	})
+}
+
+func (p *instrumentedPrism) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error {
+	attributes := []attribute.KeyValue{routeTableUUIDAttribute.String(routeTableUUID)}
+	return p.observe(ctx, "CreateRoute", attributes, func(ctx context.Context) error {
+		return p.prism.CreateRoute(ctx, routeTableUUID, route)
+	})
+}
+
+func (p *instrumentedPrism) DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error {
+	attributes := []attribute.KeyValue{routeTableUUIDAttribute.String(routeTableUUID), routeUUIDAttribute.String(routeUUID)}
+	return p.observe(ctx, "DeleteRoute", attributes, func(ctx context.Context) error {
+		return p.prism.DeleteRoute(ctx, routeTableUUID, routeUUID)
+	})
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/component-base/metrics/testutil"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+var _ = Describe("Test Prism metrics", func() { //nolint:typecheck
+	var (
+		ctx   context.Context
+		prism interfaces.Prism
+	)
+
+	counterValue := func(method string, status string) float64 {
+		value, err := testutil.GetCounterMetricValue(prismRequests.WithLabelValues(method, status))
+		Expect(err).ToNot(HaveOccurred())
+		return value
+	}
+
+	BeforeEach(func() {
+		registerMetrics()
+		ctx = context.Background()
+		mockEnvironment, err := mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+		Expect(err).ToNot(HaveOccurred())
+		mockPrism, err := mock.CreateMockClient(*mockEnvironment).Get()
+		Expect(err).ToNot(HaveOccurred())
+		prism = newInstrumentedPrism(mockPrism, nil)
+	})
+
+	It("should count requests by method and status", func() {
+		successes := counterValue("GetVM", "success")
+		notFound := counterValue("GetVM", "not_found")
+		durations, err := testutil.GetHistogramMetricCount(prismRequestDuration.WithLabelValues("GetVM"))
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = prism.GetVM(ctx, "00000000-0000-0000-0000-000000000999")
+		Expect(err).To(MatchError(interfaces.ErrNotFound))
+
+		Expect(counterValue("GetVM", "success")).To(Equal(successes + 1))
+		Expect(counterValue("GetVM", "not_found")).To(Equal(notFound + 1))
+		Expect(testutil.GetHistogramMetricCount(prismRequestDuration.WithLabelValues("GetVM"))).To(Equal(durations + 2))
+		Expect(testutil.GetGaugeMetricValue(prismRequestsInFlight.WithLabelValues("GetVM"))).To(BeZero())
+	})
+
+	It("should classify errors by status", func() {
+		for err, status := range map[error]string{
+			nil:                        "success",
+			interfaces.ErrNotFound:     "not_found",
+			interfaces.ErrUnauthorized: "unauthorized",
+			interfaces.ErrThrottled:    "throttled",
+			interfaces.ErrUnavailable:  "unavailable",
+			context.Canceled:           "canceled",
+			interfaces.ErrCircuitOpen:  "error",
+		} {
+			Expect(prismRequestStatus(err)).To(Equal(status), "error: %v", err)
+		}
+	})
+
+	It("should count instance operations by result", func() {
+		exists, err := testutil.GetCounterMetricValue(instanceOperations.WithLabelValues("InstanceExists", "exists"))
+		Expect(err).ToNot(HaveOccurred())
+		recordInstanceOperation("InstanceExists", outcome(true, "exists", "not_found"), nil)
+		Expect(testutil.GetCounterMetricValue(instanceOperations.WithLabelValues("InstanceExists", "exists"))).To(Equal(exists + 1))
+	})
+})
@@ -0,0 +1,272 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	clusterModels "github.com/nutanix/ntnx-api-golang-clients/clustermgmt-go-client/v4/models/clustermgmt/v4/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	prismModels "github.com/nutanix/ntnx-api-golang-clients/prism-go-client/v4/models/prism/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+const (
+	defaultPrismClientQPS           = 20
+	defaultPrismClientBurst         = 40
+	defaultPrismRetryMaxAttempts    = 4
+	defaultPrismRetryInitialBackoff = 500 * time.Millisecond
+	defaultPrismRetryMaxBackoff     = 10 * time.Second
+	defaultPrismRetryJitter         = 0.2
+)
+
+var prismErrorClasses = map[config.PrismErrorClass]error{
+	config.ThrottledPrismErrorClass:    interfaces.ErrThrottled,
+	config.UnavailablePrismErrorClass:  interfaces.ErrUnavailable,
+	config.UnauthorizedPrismErrorClass: interfaces.ErrUnauthorized,
+}
+
+// prismClientPolicy rate limits and retries requests to a Prism Central, and suspends them while the
+// circuit breaker is open. It is shared by all clients of the Prism Central, so it applies to the
+// cloud provider as a whole, while each Prism Central has its own policy so that one which is
+// throttling or unavailable does not hold up requests to the others.
+type prismClientPolicy struct {
+	limiter     flowcontrol.RateLimiter
+	maxAttempts int
+	backoff     wait.Backoff
+	retryOn     []error
+	// breaker is nil if the circuit breaker is disabled
+	breaker *circuitBreaker
+}
+
+func newPrismClientPolicy(clientConfig config.PrismClient, breaker *circuitBreaker) *prismClientPolicy {
+	qps := clientConfig.QPS
+	if qps == 0 {
+		qps = defaultPrismClientQPS
+	}
+	burst := clientConfig.Burst
+	if burst == 0 {
+		burst = defaultPrismClientBurst
+	}
+
+	retry := clientConfig.Retry
+	maxAttempts := retry.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultPrismRetryMaxAttempts
+	}
+	jitter := defaultPrismRetryJitter
+	if retry.Jitter != nil {
+		jitter = *retry.Jitter
+	}
+	retryOn := []error{interfaces.ErrThrottled, interfaces.ErrUnavailable}
+	if len(retry.RetryOn) > 0 {
+		retryOn = make([]error, 0, len(retry.RetryOn))
+		for _, class := range retry.RetryOn {
+			retryOn = append(retryOn, prismErrorClasses[class])
+		}
+	}
+
+	return &prismClientPolicy{
+		limiter:     flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		maxAttempts: maxAttempts,
+		backoff: wait.Backoff{
+			Duration: durationOrDefault(retry.InitialBackoff.Duration, defaultPrismRetryInitialBackoff),
+			Factor:   2,
+			Jitter:   jitter,
+			Steps:    maxAttempts,
+			Cap:      durationOrDefault(retry.MaxBackoff.Duration, defaultPrismRetryMaxBackoff),
+		},
+		retryOn: retryOn,
+		breaker: breaker,
+	}
+}
+
+func (p *prismClientPolicy) isRetryable(err error) bool {
+	for _, retryable := range p.retryOn {
+		if errors.Is(err, retryable) {
+			return true
+		}
+	}
+	return false
+}
+
+// do calls f unless the circuit breaker is open. The retries of a request count as a single request
+// for the circuit breaker.
+func (p *prismClientPolicy) do(ctx context.Context, retry bool, f func() error) error {
+	if p.breaker == nil {
+		return p.doWithRetry(ctx, retry, f)
+	}
+	ticket, err := p.breaker.allow()
+	if err != nil {
+		return err
+	}
+	err = p.doWithRetry(ctx, retry, f)
+	p.breaker.done(ticket, err)
+	return err
+}
+
+// doWithRetry calls f once the rate limiter allows it. If retry is set, f is called again with
+// exponential backoff while it fails with a retryable error, until the attempts are exhausted or ctx
+// is done.
+func (p *prismClientPolicy) doWithRetry(ctx context.Context, retry bool, f func() error) error {
+	backoff := p.backoff
+	for attempt := 1; ; attempt++ {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return err
+		}
+		err := f()
+		if err == nil || !retry || attempt >= p.maxAttempts || !p.isRetryable(err) {
+			return err
+		}
+
+		delay := backoff.Step()
+		klog.V(1).Infof("retrying Prism request in %s after attempt %d failed: %v", delay, attempt, err) //nolint:typecheck
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func doValue[T any](ctx context.Context, p *prismClientPolicy, retry bool, f func() (T, error)) (T, error) {
+	var value T
+	err := p.do(ctx, retry, func() error {
+		var err error
+		value, err = f()
+		return err
+	})
+	return value, err
+}
+
+// retryingPrism applies the client policy to all Prism calls. Calls which create, update or delete
+// entities are only rate limited, as they also wait for the resulting task and a retry could repeat
+// an operation which already succeeded. Their callers are requeued by their controllers instead.
+type retryingPrism struct {
+	prism  interfaces.Prism
+	policy *prismClientPolicy
+}
+
+func newRetryingPrism(prism interfaces.Prism, policy *prismClientPolicy) interfaces.Prism {
+	return &retryingPrism{prism: prism, policy: policy}
+}
+
+func (p *retryingPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	return doValue(ctx, p.policy, true, func() (*vmmModels.Vm, error) {
+		return p.prism.GetVM(ctx, vmUUID)
+	})
+}
+
+func (p *retryingPrism) ListVMs(ctx context.Context, filter string) ([]vmmModels.Vm, error) {
+	return doValue(ctx, p.policy, true, func() ([]vmmModels.Vm, error) {
+		return p.prism.ListVMs(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) GetCluster(ctx context.Context, clusterUUID string) (*clusterModels.Cluster, error) {
+	return doValue(ctx, p.policy, true, func() (*clusterModels.Cluster, error) {
+		return p.prism.GetCluster(ctx, clusterUUID)
+	})
+}
+
+func (p *retryingPrism) ListAllCluster(ctx context.Context) ([]clusterModels.Cluster, error) {
+	return doValue(ctx, p.policy, true, func() ([]clusterModels.Cluster, error) {
+		return p.prism.ListAllCluster(ctx)
+	})
+}
+
+func (p *retryingPrism) GetCategory(ctx context.Context, categoryUUID string) (*prismModels.Category, error) {
+	return doValue(ctx, p.policy, true, func() (*prismModels.Category, error) {
+		return p.prism.GetCategory(ctx, categoryUUID)
+	})
+}
+
+func (p *retryingPrism) ListCategories(ctx context.Context, filter string) ([]prismModels.Category, error) {
+	return doValue(ctx, p.policy, true, func() ([]prismModels.Category, error) {
+		return p.prism.ListCategories(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) GetClusterHost(ctx context.Context, clusterUUID string, hostUUID string) (*clusterModels.Host, error) {
+	return doValue(ctx, p.policy, true, func() (*clusterModels.Host, error) {
+		return p.prism.GetClusterHost(ctx, clusterUUID, hostUUID)
+	})
+}
+
+func (p *retryingPrism) GetSubnet(ctx context.Context, subnetUUID string) (*networkingModels.Subnet, error) {
+	return doValue(ctx, p.policy, true, func() (*networkingModels.Subnet, error) {
+		return p.prism.GetSubnet(ctx, subnetUUID)
+	})
+}
+
+func (p *retryingPrism) ListLoadBalancerSessions(ctx context.Context, filter string) ([]networkingModels.LoadBalancerSession, error) {
+	return doValue(ctx, p.policy, true, func() ([]networkingModels.LoadBalancerSession, error) {
+		return p.prism.ListLoadBalancerSessions(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) CreateLoadBalancerSession(ctx context.Context, session *networkingModels.LoadBalancerSession) error {
+	return p.policy.do(ctx, false, func() error {
+		return p.prism.CreateLoadBalancerSession(ctx, session)
+	})
+}
+
+func (p *retryingPrism) UpdateLoadBalancerSession(ctx context.Context, sessionUUID string, session *networkingModels.LoadBalancerSession) error {
+	return p.policy.do(ctx, false, func() error {
+		return p.prism.UpdateLoadBalancerSession(ctx, sessionUUID, session)
+	})
+}
+
+func (p *retryingPrism) DeleteLoadBalancerSession(ctx context.Context, sessionUUID string) error {
+	return p.policy.do(ctx, false, func() error {
+		return p.prism.DeleteLoadBalancerSession(ctx, sessionUUID)
+	})
+}
+
+func (p *retryingPrism) ListRouteTables(ctx context.Context, filter string) ([]networkingModels.RouteTable, error) {
+	return doValue(ctx, p.policy, true, func() ([]networkingModels.RouteTable, error) {
+		return p.prism.ListRouteTables(ctx, filter)
+	})
+}
+
+func (p *retryingPrism) ListRoutes(ctx context.Context, routeTableUUID string, filter string) ([]networkingModels.Route, error) {
+	return doValue(ctx, p.policy, true, func() ([]networkingModels.Route, error) {
+		return p.prism.ListRoutes(ctx, routeTableUUID, filter)
+	})
+}
+
+func (p *retryingPrism) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error {
+	return p.policy.do(ctx, false, func() error {
+		return p.prism.CreateRoute(ctx, routeTableUUID, route)
+	})
+}
+
+func (p *retryingPrism) DeleteRoute(ctx context.Context, routeTableUUID string, routeUUID string) error {
+	return p.policy.do(ctx, false, func() error {
+		return p.prism.DeleteRoute(ctx, routeTableUUID, routeUUID)
+	})
+}
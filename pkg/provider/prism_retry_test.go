@@ -0,0 +1,158 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	vmmModels "github.com/nutanix/ntnx-api-golang-clients/vmm-go-client/v4/models/vmm/v4/ahv/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// flakyPrism fails the first calls with err before passing them through to the mock Prism.
+type flakyPrism struct {
+	interfaces.Prism
+	failures int
+	err      error
+	calls    int
+}
+
+func (p *flakyPrism) fail() error {
+	p.calls++
+	if p.calls <= p.failures {
+		return fmt.Errorf("%w: attempt %d", p.err, p.calls)
+	}
+	return nil
+}
+
+func (p *flakyPrism) GetVM(ctx context.Context, vmUUID string) (*vmmModels.Vm, error) {
+	if err := p.fail(); err != nil {
+		return nil, err
+	}
+	return p.Prism.GetVM(ctx, vmUUID)
+}
+
+func (p *flakyPrism) CreateRoute(ctx context.Context, routeTableUUID string, route *networkingModels.Route) error {
+	if err := p.fail(); err != nil {
+		return err
+	}
+	return p.Prism.CreateRoute(ctx, routeTableUUID, route)
+}
+
+var _ = Describe("Test Prism client policy", func() { //nolint:typecheck
+	var (
+		ctx    context.Context
+		flaky  *flakyPrism
+		policy config.PrismClient
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		mockEnvironment, err := mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+		Expect(err).ToNot(HaveOccurred())
+		mockPrism, err := mock.CreateMockClient(*mockEnvironment).Get()
+		Expect(err).ToNot(HaveOccurred())
+		flaky = &flakyPrism{Prism: mockPrism}
+		policy = config.PrismClient{
+			Retry: config.PrismClientRetry{
+				MaxAttempts:    3,
+				InitialBackoff: metav1.Duration{Duration: time.Millisecond},
+				MaxBackoff:     metav1.Duration{Duration: 2 * time.Millisecond},
+			},
+		}
+	})
+
+	It("should retry throttled and unavailable requests", func() {
+		for _, err := range []error{interfaces.ErrThrottled, interfaces.ErrUnavailable} {
+			flaky.calls, flaky.failures, flaky.err = 0, 2, err
+			prism := newRetryingPrism(flaky, newPrismClientPolicy(policy, nil))
+			vm, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(*vm.ExtId).To(Equal(mock.MockVMPoweredOnUUID))
+			Expect(flaky.calls).To(Equal(3))
+		}
+	})
+
+	It("should give up after the maximum attempts", func() {
+		flaky.failures, flaky.err = 5, interfaces.ErrUnavailable
+		prism := newRetryingPrism(flaky, newPrismClientPolicy(policy, nil))
+		_, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+		Expect(err).To(MatchError(interfaces.ErrUnavailable))
+		Expect(flaky.calls).To(Equal(3))
+	})
+
+	It("should not retry errors which are not retryable", func() {
+		flaky.failures, flaky.err = 1, interfaces.ErrNotFound
+		prism := newRetryingPrism(flaky, newPrismClientPolicy(policy, nil))
+		_, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+		Expect(err).To(MatchError(interfaces.ErrNotFound))
+		Expect(flaky.calls).To(Equal(1))
+	})
+
+	It("should only retry the configured error classes", func() {
+		policy.Retry.RetryOn = []config.PrismErrorClass{config.UnauthorizedPrismErrorClass}
+		flaky.failures, flaky.err = 1, interfaces.ErrThrottled
+		prism := newRetryingPrism(flaky, newPrismClientPolicy(policy, nil))
+		_, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+		Expect(err).To(MatchError(interfaces.ErrThrottled))
+		Expect(flaky.calls).To(Equal(1))
+	})
+
+	It("should not retry requests which change entities", func() {
+		flaky.failures, flaky.err = 1, interfaces.ErrUnavailable
+		prism := newRetryingPrism(flaky, newPrismClientPolicy(policy, nil))
+		err := prism.CreateRoute(ctx, mock.MockRouteTableUUID, &networkingModels.Route{Name: ptr.To("route")})
+		Expect(err).To(MatchError(interfaces.ErrUnavailable))
+		Expect(flaky.calls).To(Equal(1))
+	})
+
+	It("should stop retrying once the context is done", func() {
+		policy.Retry.InitialBackoff = metav1.Duration{Duration: time.Minute}
+		policy.Retry.MaxBackoff = metav1.Duration{Duration: time.Minute}
+		flaky.failures, flaky.err = 5, interfaces.ErrUnavailable
+		prism := newRetryingPrism(flaky, newPrismClientPolicy(policy, nil))
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		_, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+		Expect(err).To(MatchError(interfaces.ErrUnavailable))
+		Expect(flaky.calls).To(Equal(1))
+	})
+
+	It("should rate limit requests", func() {
+		policy.QPS = 20
+		policy.Burst = 1
+		prism := newRetryingPrism(flaky, newPrismClientPolicy(policy, nil))
+		start := time.Now()
+		for range 3 {
+			_, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(time.Since(start)).To(BeNumerically(">=", 80*time.Millisecond))
+	})
+})
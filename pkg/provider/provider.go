@@ -17,11 +17,16 @@ limitations under the License.
 package provider
 
 import (
+	"context"
 	"fmt"
 	"io"
 
 	clientset "k8s.io/client-go/kubernetes"
 	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/cloud-provider/app"
+	cloudcontrollerconfig "k8s.io/cloud-provider/app/config"
+	genericcontrollermanager "k8s.io/controller-manager/app"
+	"k8s.io/controller-manager/controller"
 	"k8s.io/klog/v2"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
@@ -31,10 +36,17 @@ import (
 type NtnxCloud struct {
 	name string
 
-	client      clientset.Interface
-	config      config.Config
-	manager     *nutanixManager
-	instancesV2 cloudprovider.InstancesV2
+	client       clientset.Interface
+	config       config.Config
+	manager      *nutanixManager
+	instancesV2  cloudprovider.InstancesV2
+	vipAllocator *vipAllocator
+
+	flowVPCLoadBalancer *flowVPCLoadBalancer
+	routes              *vpcRoutes
+	// configWatcher reloads the config file once the cloud provider is initialized, nil if the
+	// path of the config file is not known
+	configWatcher *configWatcher
 }
 
 func init() {
@@ -67,6 +79,28 @@ func newNtnxCloud(configReader io.Reader) (cloudprovider.Interface, error) {
 		instancesV2: newInstancesV2(nutanixManager),
 	}
 
+	if lbConfig := nutanixConfig.LoadBalancer; lbConfig != nil {
+		if len(lbConfig.IPPools) > 0 {
+			ntnx.vipAllocator, err = newVIPAllocator(lbConfig)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if lbConfig.Type == config.FlowVPCLoadBalancerType {
+			ntnx.flowVPCLoadBalancer, err = newFlowVPCLoadBalancer(nutanixManager, lbConfig, ntnx.vipAllocator)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if nutanixConfig.Routes != nil {
+		ntnx.routes, err = newVPCRoutes(nutanixManager, nutanixConfig.Routes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return ntnx, err
 }
 
@@ -77,11 +111,28 @@ func (nc *NtnxCloud) Initialize(clientBuilder cloudprovider.ControllerClientBuil
 	klog.Info("Initializing client ...") //nolint:typecheck
 	nc.addKubernetesClient(clientBuilder.ClientOrDie("cloud-provider-nutanix"))
 	klog.Infof("Client initialized") //nolint:typecheck
+	if nc.configWatcher != nil {
+		go nc.configWatcher.run(stopCh)
+	}
+	go func() {
+		<-stopCh
+		nc.manager.shutdownTracer()
+	}()
+}
+
+// WatchConfigFile makes the cloud provider reload the config file it was created from whenever the
+// file changes. Invalid changes are rejected and the last valid config is kept. The file is watched
+// once the cloud provider is initialized.
+func (nc *NtnxCloud) WatchConfigFile(path string) {
+	nc.configWatcher = newConfigWatcher(path, nc.manager)
 }
 
 func (nc *NtnxCloud) addKubernetesClient(kclient clientset.Interface) {
 	nc.client = kclient
 	nc.manager.setKubernetesClient(kclient)
+	if nc.vipAllocator != nil {
+		nc.vipAllocator.client = kclient
+	}
 }
 
 // ProviderName returns the cloud provider ID.
@@ -95,11 +146,46 @@ func (nc *NtnxCloud) HasClusterID() bool {
 }
 
 func (nc *NtnxCloud) LoadBalancer() (cloudprovider.LoadBalancer, bool) {
-	return nc, false
+	return nc, nc.config.LoadBalancer != nil
 }
 
+// Routes is only supported when the cloud config opts in, otherwise the route controller
+// does not start.
 func (nc *NtnxCloud) Routes() (cloudprovider.Routes, bool) {
-	return nil, false
+	if nc.routes == nil {
+		return nil, false
+	}
+	return nc.routes, true
+}
+
+// StartServiceControllerWrapper returns the InitFunc of the service controller, which is only
+// started if the cloud config has a loadBalancer section.
+func StartServiceControllerWrapper(initContext app.ControllerInitContext, completedConfig *cloudcontrollerconfig.CompletedConfig, cloud cloudprovider.Interface) app.InitFunc {
+	return startIfSupported("loadBalancer", func() bool {
+		_, ok := cloud.LoadBalancer()
+		return ok
+	}, app.StartServiceControllerWrapper(initContext, completedConfig, cloud))
+}
+
+// StartRouteControllerWrapper returns the InitFunc of the route controller, which is only started if
+// the cloud config has a routes section.
+func StartRouteControllerWrapper(initContext app.ControllerInitContext, completedConfig *cloudcontrollerconfig.CompletedConfig, cloud cloudprovider.Interface) app.InitFunc {
+	return startIfSupported("routes", func() bool {
+		_, ok := cloud.Routes()
+		return ok
+	}, app.StartRouteControllerWrapper(initContext, completedConfig, cloud))
+}
+
+// startIfSupported skips the controller started by initFunc if the cloud provider does not support
+// it, so that it does not fail to start on clusters that do not configure the feature.
+func startIfSupported(section string, supported func() bool, initFunc app.InitFunc) app.InitFunc {
+	return func(ctx context.Context, controllerContext genericcontrollermanager.ControllerContext) (controller.Interface, bool, error) {
+		if !supported() {
+			klog.Infof("Not starting the controller as the cloud config has no %s section", section) //nolint:typecheck
+			return nil, false, nil
+		}
+		return initFunc(ctx, controllerContext)
+	}
 }
 
 func (nc *NtnxCloud) Clusters() (cloudprovider.Clusters, bool) {
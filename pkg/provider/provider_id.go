@@ -0,0 +1,63 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+)
+
+// providerID identifies the VM of a node. Provider IDs of the UUID format nutanix://<vm-uuid> only
+// hold the VM UUID, provider IDs of the qualified format
+// nutanix://<prism-central-uuid>/<cluster-uuid>/<vm-uuid> also hold the UUIDs of the Prism Central
+// and the Prism Element cluster of the VM.
+type providerID struct {
+	prismCentralUUID string
+	clusterUUID      string
+	vmUUID           string
+}
+
+// parseProviderID parses a provider ID of either format.
+func parseProviderID(id string) (providerID, error) {
+	prefix := constants.ProviderName + "://"
+	if !strings.HasPrefix(id, prefix) {
+		return providerID{}, fmt.Errorf("provider ID %q does not start with %s", id, prefix)
+	}
+	parts := strings.Split(strings.TrimPrefix(id, prefix), "/")
+	if slices.Contains(parts, "") {
+		return providerID{}, fmt.Errorf("provider ID %q contains an empty UUID", id)
+	}
+	switch len(parts) {
+	case 1:
+		return providerID{vmUUID: parts[0]}, nil
+	case 3:
+		return providerID{prismCentralUUID: parts[0], clusterUUID: parts[1], vmUUID: parts[2]}, nil
+	}
+	return providerID{}, fmt.Errorf("provider ID %q must be of the format %s<vm-uuid> or %s<prism-central-uuid>/<cluster-uuid>/<vm-uuid>", id, prefix, prefix)
+}
+
+// String returns the provider ID in the qualified format if it holds the Prism Central and cluster
+// UUIDs, and in the UUID format otherwise.
+func (p providerID) String() string {
+	if p.prismCentralUUID == "" || p.clusterUUID == "" {
+		return fmt.Sprintf("%s://%s", constants.ProviderName, p.vmUUID)
+	}
+	return fmt.Sprintf("%s://%s/%s/%s", constants.ProviderName, p.prismCentralUUID, p.clusterUUID, p.vmUUID)
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"testing"
+)
+
+func TestParseProviderID(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		want    providerID
+		wantErr bool
+	}{
+		{
+			name: "UUID format",
+			id:   "nutanix://vm-uuid",
+			want: providerID{vmUUID: "vm-uuid"},
+		},
+		{
+			name: "qualified format",
+			id:   "nutanix://pc-uuid/cluster-uuid/vm-uuid",
+			want: providerID{prismCentralUUID: "pc-uuid", clusterUUID: "cluster-uuid", vmUUID: "vm-uuid"},
+		},
+		{
+			name:    "other provider",
+			id:      "aws:///us-east-1a/i-0123456789",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			id:      "",
+			wantErr: true,
+		},
+		{
+			name:    "empty VM UUID",
+			id:      "nutanix://",
+			wantErr: true,
+		},
+		{
+			name:    "empty cluster UUID",
+			id:      "nutanix://pc-uuid//vm-uuid",
+			wantErr: true,
+		},
+		{
+			name:    "two UUIDs",
+			id:      "nutanix://cluster-uuid/vm-uuid",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProviderID(tt.id)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProviderID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseProviderID() = %+v, want %+v", got, tt.want)
+			}
+			if !tt.wantErr && got.String() != tt.id {
+				t.Errorf("String() = %s, want %s", got.String(), tt.id)
+			}
+		})
+	}
+}
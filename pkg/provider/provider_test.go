@@ -19,13 +19,21 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	credentialTypes "github.com/nutanix-cloud-native/prism-go-client/environment/credentials"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/cloud-provider/app"
+	genericcontrollermanager "k8s.io/controller-manager/app"
+	"k8s.io/utils/ptr"
 
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
@@ -97,6 +105,17 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 		})
 	})
 
+	Context("Test controller wrappers", func() {
+		It("should not start the service and route controllers without their config sections", func() {
+			for _, wrapper := range []app.InitFuncConstructor{StartServiceControllerWrapper, StartRouteControllerWrapper} {
+				c, enabled, err := wrapper(app.ControllerInitContext{}, nil, &ntnxCloud)(context.Background(), genericcontrollermanager.ControllerContext{})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(enabled).To(BeFalse())
+				Expect(c).To(BeNil())
+			}
+		})
+	})
+
 	Context("Test Clusters", func() {
 		It("should not support clusters functionality", func() {
 			nc, b := ntnxCloud.Clusters()
@@ -176,9 +195,8 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 		})
 
 		It("should default to Prism topology Discovery", func() {
-			c := config.Config{
-				TopologyDiscovery: config.TopologyDiscovery{},
-			}
+			c := mock.GenerateMockConfig()
+			c.TopologyDiscovery = config.TopologyDiscovery{}
 			cBytes, err := json.Marshal(c)
 			Expect(err).ToNot(HaveOccurred())
 			cReader := bytes.NewReader(cBytes)
@@ -186,17 +204,282 @@ var _ = Describe("Test Provider", func() { //nolint:typecheck
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("should reject unknown fields", func() {
+			_, err := newNtnxCloud(strings.NewReader(`{
+				"prismCentral": {"address": "pc.example.com", "credentialRef": {"kind": "Secret", "name": "creds"}},
+				"ignoredNodeIps": ["10.0.0.1"]
+			}`))
+			Expect(err).To(MatchError(ContainSubstring(`unknown field "ignoredNodeIps"`)))
+		})
+
+		It("should accept multiple Prism Centrals", func() {
+			c, err := config.NewConfigFromBytes([]byte(`
+prismCentrals:
+  - name: pc-1
+    address: pc-1.example.com
+    credentialRef:
+      name: pc-1-creds
+  - name: pc-2
+    address: pc-2.example.com
+    port: 9441
+    credentialRef:
+      name: pc-2-creds
+`))
+			Expect(err).ToNot(HaveOccurred())
+			endpoints := c.PrismCentralEndpoints()
+			Expect(endpoints).To(HaveLen(2))
+			Expect(endpoints[0].Name).To(Equal("pc-1"))
+			Expect(endpoints[0].Port).To(BeEquivalentTo(config.DefaultPrismCentralPort))
+			Expect(endpoints[1].Address).To(Equal("pc-2.example.com"))
+			Expect(endpoints[1].Port).To(BeEquivalentTo(9441))
+			Expect(endpoints[1].CredentialRef.Kind).To(Equal(credentialTypes.SecretKind))
+		})
+
+		It("should reject Prism Central environment variables with multiple Prism Centrals", func() {
+			GinkgoT().Setenv(config.PrismAddressEnv, "pc.override.example.com")
+			GinkgoT().Setenv(config.CredentialSecretNameEnv, "creds")
+			_, err := config.NewConfigFromBytes([]byte(`
+prismCentrals:
+  - name: pc-1
+    address: pc-1.example.com
+    credentialRef:
+      name: pc-1-creds
+`))
+			Expect(err).To(MatchError(ContainSubstring(config.PrismAddressEnv + " cannot be used with prismCentrals")))
+			Expect(err).To(MatchError(ContainSubstring(config.CredentialSecretNameEnv + " cannot be used with prismCentrals")))
+			Expect(err.Error()).ToNot(ContainSubstring("prismCentral: Forbidden"))
+		})
+
+		It("should let environment variables override the config file", func() {
+			GinkgoT().Setenv(config.PrismAddressEnv, "pc.override.example.com")
+			GinkgoT().Setenv(config.PrismPortEnv, "9441")
+			GinkgoT().Setenv(config.TopologyTypeEnv, string(config.CategoriesTopologyDiscoveryType))
+			GinkgoT().Setenv(config.TopologyZoneCategoryEnv, "zone")
+			GinkgoT().Setenv(config.IgnoredNodeIPsEnv, "10.0.0.1, 10.0.1.0/24")
+			cBytes, err := json.Marshal(mock.GenerateMockConfig())
+			Expect(err).ToNot(HaveOccurred())
+
+			c, err := config.NewConfigFromBytes(cBytes)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c.PrismCentral.Address).To(Equal("pc.override.example.com"))
+			Expect(c.PrismCentral.Port).To(BeEquivalentTo(9441))
+			Expect(c.PrismCentral.CredentialRef.Name).To(Equal(mock.GenerateMockConfig().PrismCentral.CredentialRef.Name))
+			Expect(c.TopologyDiscovery.Type).To(Equal(config.CategoriesTopologyDiscoveryType))
+			Expect(c.TopologyDiscovery.TopologyCategories.ZoneCategory).To(Equal("zone"))
+			Expect(c.IgnoredNodeIPs).To(Equal([]string{"10.0.0.1", "10.0.1.0/24"}))
+
+			GinkgoT().Setenv(config.PrismPortEnv, "https")
+			GinkgoT().Setenv(config.TopologyTypeEnv, "")
+			c, err = config.NewConfigFromBytes(cBytes)
+			Expect(err).To(MatchError(ContainSubstring(config.PrismPortEnv)))
+			Expect(c.TopologyDiscovery.Type).To(Equal(config.PrismTopologyDiscoveryType))
+		})
+
+		It("should fail if load balancer is enabled without IP pools", func() {
+			c := mock.GenerateMockConfig()
+			c.LoadBalancer = &config.LoadBalancer{}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should fail if a load balancer IP pool contains invalid addresses", func() {
+			c := mock.GenerateMockConfig()
+			c.LoadBalancer = &config.LoadBalancer{
+				IPPools: []config.IPPool{{Name: "pool", Addresses: []string{"10.0.0.300"}}},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should enable load balancer support when IP pools are configured", func() {
+			c := mock.GenerateMockConfig()
+			c.LoadBalancer = &config.LoadBalancer{
+				IPPools: []config.IPPool{{Name: "pool", Addresses: []string{"10.0.0.0/24"}}},
+			}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			cloud, err := newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).ToNot(HaveOccurred())
+			_, ok := cloud.LoadBalancer()
+			Expect(ok).To(BeTrue())
+		})
+
+		It("should fail if routes are enabled without VPC", func() {
+			c := mock.GenerateMockConfig()
+			c.Routes = &config.Routes{}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should enable route support when routes are configured", func() {
+			c := mock.GenerateMockConfig()
+			c.Routes = &config.Routes{VPCUUID: mock.MockVPCUUID}
+			cBytes, err := json.Marshal(c)
+			Expect(err).ToNot(HaveOccurred())
+			cloud, err := newNtnxCloud(bytes.NewReader(cBytes))
+			Expect(err).ToNot(HaveOccurred())
+			routes, ok := cloud.Routes()
+			Expect(ok).To(BeTrue())
+			Expect(routes).ToNot(BeNil())
+		})
+
+		It("should fail if invalid node IP families are passed", func() {
+			for _, families := range [][]config.IPFamily{
+				{"IPv5"},
+				{config.IPv4IPFamily, config.IPv4IPFamily},
+				{config.IPv4IPFamily, config.IPv6IPFamily, config.IPv4IPFamily},
+			} {
+				c := mock.GenerateMockConfig()
+				c.NodeIPFamilies = families
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "families: %v", families)
+			}
+		})
+
+		It("should fail if invalid node address rules are passed", func() {
+			for _, rule := range []config.NodeAddressRule{
+				{Type: "Hostname", CIDRs: []string{"10.0.0.0/8"}},
+				{Type: config.ExternalIPNodeAddressType},
+				{Type: config.ExternalIPNodeAddressType, CIDRs: []string{"10.0.0.300/8"}},
+			} {
+				c := mock.GenerateMockConfig()
+				c.NodeAddressRules = []config.NodeAddressRule{rule}
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "rule: %v", rule)
+			}
+		})
+
+		It("should fail if invalid instance classes are passed", func() {
+			for _, classes := range [][]config.InstanceClass{
+				{{VCPUs: 4}},
+				{{Name: "invalid name"}},
+				{{Name: "small"}, {Name: "small"}},
+				{{Name: "small", MemoryGiB: -1}},
+			} {
+				c := mock.GenerateMockConfig()
+				c.InstanceTypes = &config.InstanceTypes{Classes: classes}
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "classes: %v", classes)
+			}
+		})
+
+		It("should fail if invalid category labels are passed", func() {
+			for _, categoryLabels := range []*config.CategoryLabels{
+				{},
+				{Domain: "invalid domain", Keys: []string{"key"}},
+			} {
+				c := mock.GenerateMockConfig()
+				c.CategoryLabels = categoryLabels
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "categoryLabels: %v", categoryLabels)
+			}
+		})
+
+		It("should fail if invalid VM lookup settings are passed", func() {
+			for _, vmLookup := range []*config.VMLookup{
+				{},
+				{Fallbacks: []config.VMLookupStrategy{"MACAddress"}},
+				{Fallbacks: []config.VMLookupStrategy{config.NameVMLookupStrategy, config.NameVMLookupStrategy}},
+				{Fallbacks: []config.VMLookupStrategy{config.AnnotationVMLookupStrategy}, Annotation: "invalid annotation"},
+			} {
+				c := mock.GenerateMockConfig()
+				c.VMLookup = vmLookup
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "vmLookup: %v", vmLookup)
+			}
+		})
+
+		It("should fail if invalid VM power states are passed", func() {
+			for _, vmPowerStates := range []map[string]config.NodeState{
+				{"SUSPENDED": config.ShutdownNodeState},
+				{"PAUSED": "Stopped"},
+			} {
+				c := mock.GenerateMockConfig()
+				c.VMPowerStates = vmPowerStates
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "vmPowerStates: %v", vmPowerStates)
+			}
+		})
+
+		It("should fail if invalid prism cache settings are passed", func() {
+			for _, prismCache := range []config.PrismCache{
+				{MaxEntries: -1},
+				{VMTTL: metav1.Duration{Duration: -time.Second}},
+				{NotFoundTTL: metav1.Duration{Duration: -time.Second}},
+			} {
+				c := mock.GenerateMockConfig()
+				c.PrismCache = prismCache
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "prismCache: %v", prismCache)
+			}
+		})
+
+		It("should fail if invalid prism client settings are passed", func() {
+			for _, prismClient := range []config.PrismClient{
+				{QPS: -1},
+				{Burst: -1},
+				{Retry: config.PrismClientRetry{MaxAttempts: -1}},
+				{Retry: config.PrismClientRetry{InitialBackoff: metav1.Duration{Duration: time.Minute}, MaxBackoff: metav1.Duration{Duration: time.Second}}},
+				{Retry: config.PrismClientRetry{Jitter: ptr.To(-0.5)}},
+				{Retry: config.PrismClientRetry{RetryOn: []config.PrismErrorClass{"NotFound"}}},
+				{CircuitBreaker: config.PrismCircuitBreaker{FailureThreshold: -1}},
+				{CircuitBreaker: config.PrismCircuitBreaker{OpenDuration: metav1.Duration{Duration: -time.Second}}},
+			} {
+				c := mock.GenerateMockConfig()
+				c.PrismClient = prismClient
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "prismClient: %v", prismClient)
+			}
+		})
+
+		It("should fail if invalid tracing settings are passed", func() {
+			for _, tracing := range []*config.Tracing{
+				{},
+				{Exporter: "Jaeger"},
+				{Exporter: config.FileTracingExporter},
+				{Exporter: config.StdoutTracingExporter, SamplingRatio: ptr.To(1.5)},
+			} {
+				c := mock.GenerateMockConfig()
+				c.Tracing = tracing
+				cBytes, err := json.Marshal(c)
+				Expect(err).ToNot(HaveOccurred())
+				_, err = newNtnxCloud(bytes.NewReader(cBytes))
+				Expect(err).To(HaveOccurred(), "tracing: %v", tracing)
+			}
+		})
+
 		It("should return valid NtnxCloud when valid reader is passed", func() {
-			config := config.Config{
-				TopologyDiscovery: config.TopologyDiscovery{
-					Type: config.CategoriesTopologyDiscoveryType,
-					TopologyCategories: &config.TopologyCategories{
-						RegionCategory: mock.MockDefaultRegion,
-						ZoneCategory:   mock.MockDefaultZone,
-					},
+			c := mock.GenerateMockConfig()
+			c.TopologyDiscovery = config.TopologyDiscovery{
+				Type: config.CategoriesTopologyDiscoveryType,
+				TopologyCategories: &config.TopologyCategories{
+					RegionCategory: mock.MockDefaultRegion,
+					ZoneCategory:   mock.MockDefaultZone,
 				},
 			}
-			cJson, err := json.Marshal(config)
+			cJson, err := json.Marshal(c)
 			Expect(err).ToNot(HaveOccurred())
 			validReader := bytes.NewReader(cJson)
 			_, err = newNtnxCloud(validReader)
@@ -0,0 +1,275 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	networkingCommonModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/common/v1/config"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+const routeDescriptionPrefix = "Kubernetes pod CIDR route for node "
+
+// vpcRoutes implements cloudprovider.Routes with static routes in the route table of a
+// Flow Virtual Networking VPC. Each route sends the pod CIDR of a node to its InternalIP.
+type vpcRoutes struct {
+	manager *nutanixManager
+	config  *config.Routes
+
+	routeTableLock sync.Mutex
+	routeTableUUID string
+}
+
+func newVPCRoutes(manager *nutanixManager, routesConfig *config.Routes) (*vpcRoutes, error) {
+	if routesConfig == nil {
+		return nil, fmt.Errorf("routes config cannot be nil when creating VPC routes")
+	}
+	return &vpcRoutes{
+		manager:        manager,
+		config:         routesConfig,
+		routeTableUUID: routesConfig.RouteTableUUID,
+	}, nil
+}
+
+// ListRoutes lists the routes of the cluster in the VPC route table.
+func (r *vpcRoutes) ListRoutes(ctx context.Context, clusterName string) ([]*cloudprovider.Route, error) {
+	prism, err := r.manager.nutanixClient.Get()
+	if err != nil {
+		return nil, err
+	}
+	routeTableUUID, err := r.getRouteTableUUID(ctx, prism)
+	if err != nil {
+		return nil, err
+	}
+	vpcRoutes, err := prism.ListRoutes(ctx, routeTableUUID, routeNameFilter(routeNamePrefix(clusterName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes of route table %s: %w", routeTableUUID, err)
+	}
+
+	routes := make([]*cloudprovider.Route, 0, len(vpcRoutes))
+	for _, vpcRoute := range vpcRoutes {
+		if vpcRoute.ExtId == nil || vpcRoute.Destination == nil {
+			continue
+		}
+		nodeName, ok := nodeNameFromRouteDescription(ptr.Deref(vpcRoute.Description, ""), clusterName)
+		if !ok {
+			continue
+		}
+		destination := ipSubnetString(vpcRoute.Destination)
+		if destination == "" {
+			klog.Warningf("ignoring route %s with invalid destination", *vpcRoute.ExtId) //nolint:typecheck
+			continue
+		}
+		routes = append(routes, &cloudprovider.Route{
+			Name:            *vpcRoute.ExtId,
+			TargetNode:      types.NodeName(nodeName),
+			DestinationCIDR: destination,
+			Blackhole:       nexthopIPAddress(vpcRoute.Nexthop) == "",
+		})
+	}
+	klog.V(1).InfoS("ListRoutes", "routeTable", routeTableUUID, "routes", len(routes)) //nolint:typecheck
+	return routes, nil
+}
+
+// CreateRoute creates a static route sending the destination CIDR to the InternalIP of the target node.
+func (r *vpcRoutes) CreateRoute(ctx context.Context, clusterName string, nameHint string, route *cloudprovider.Route) error {
+	if route == nil {
+		return fmt.Errorf("route cannot be nil when creating route")
+	}
+	destination, err := netip.ParsePrefix(route.DestinationCIDR)
+	if err != nil {
+		return fmt.Errorf("failed to parse route destination %q: %v", route.DestinationCIDR, err)
+	}
+	nexthop, err := routeNexthopAddress(route, destination.Addr().Is4())
+	if err != nil {
+		return err
+	}
+	prism, err := r.manager.nutanixClient.Get()
+	if err != nil {
+		return err
+	}
+	routeTableUUID, err := r.getRouteTableUUID(ctx, prism)
+	if err != nil {
+		return err
+	}
+
+	vpcRoute := networkingModels.NewRoute()
+	vpcRoute.Name = ptr.To(routeName(clusterName, nameHint))
+	vpcRoute.Description = ptr.To(routeDescription(route.TargetNode, clusterName))
+	vpcRoute.VpcReference = ptr.To(r.config.VPCUUID)
+	vpcRoute.RouteType = networkingModels.ROUTETYPE_STATIC.Ref()
+	vpcRoute.Destination = newIPSubnet(destination.Masked())
+	vpcRoute.Nexthop = &networkingModels.Nexthop{
+		NexthopType:      networkingModels.NEXTHOPTYPE_IP_ADDRESS.Ref(),
+		NexthopIpAddress: newIPAddress(nexthop),
+	}
+
+	klog.Infof("creating route %s to %s via node %s in route table %s", route.DestinationCIDR, nexthop, route.TargetNode, routeTableUUID) //nolint:typecheck
+	return prism.CreateRoute(ctx, routeTableUUID, vpcRoute)
+}
+
+// DeleteRoute deletes a route returned by ListRoutes.
+func (r *vpcRoutes) DeleteRoute(ctx context.Context, clusterName string, route *cloudprovider.Route) error {
+	if route == nil || route.Name == "" {
+		return fmt.Errorf("route must have a name when deleting route")
+	}
+	prism, err := r.manager.nutanixClient.Get()
+	if err != nil {
+		return err
+	}
+	routeTableUUID, err := r.getRouteTableUUID(ctx, prism)
+	if err != nil {
+		return err
+	}
+	klog.Infof("deleting route %s to node %s from route table %s", route.DestinationCIDR, route.TargetNode, routeTableUUID) //nolint:typecheck
+	return prism.DeleteRoute(ctx, routeTableUUID, route.Name)
+}
+
+// getRouteTableUUID returns the configured route table or looks up the route table of the VPC.
+func (r *vpcRoutes) getRouteTableUUID(ctx context.Context, prism interfaces.Prism) (string, error) {
+	r.routeTableLock.Lock()
+	defer r.routeTableLock.Unlock()
+	if r.routeTableUUID != "" {
+		return r.routeTableUUID, nil
+	}
+
+	routeTables, err := prism.ListRouteTables(ctx, fmt.Sprintf("vpcReference eq '%s'", r.config.VPCUUID))
+	if err != nil {
+		return "", fmt.Errorf("failed to list route tables of VPC %s: %w", r.config.VPCUUID, err)
+	}
+	for _, routeTable := range routeTables {
+		if routeTable.ExtId != nil && ptr.Deref(routeTable.VpcReference, "") == r.config.VPCUUID {
+			r.routeTableUUID = *routeTable.ExtId
+			return r.routeTableUUID, nil
+		}
+	}
+	return "", fmt.Errorf("failed to find route table of VPC %s", r.config.VPCUUID)
+}
+
+// routeName derives the route name from the cluster name and the name hint, which is the Node UID.
+func routeName(clusterName string, nameHint string) string {
+	return routeNamePrefix(clusterName) + nameHint
+}
+
+// routeNamePrefix is the name prefix of the routes of the cluster. Sanitized and truncated cluster
+// names may be shared by several clusters, so routes are also matched by their description.
+func routeNamePrefix(clusterName string) string {
+	return fmt.Sprintf("k8s-route-%s-", resourceClusterName(clusterName))
+}
+
+func routeNameFilter(prefix string) string {
+	return fmt.Sprintf("startswith(name, '%s')", prefix)
+}
+
+func routeDescription(nodeName types.NodeName, clusterName string) string {
+	return fmt.Sprintf("%s%s in cluster %s", routeDescriptionPrefix, nodeName, clusterName)
+}
+
+// nodeNameFromRouteDescription returns the target node of a route created for the cluster.
+// Node names cannot contain spaces, so the description can be parsed unambiguously.
+func nodeNameFromRouteDescription(description string, clusterName string) (string, bool) {
+	rest, ok := strings.CutPrefix(description, routeDescriptionPrefix)
+	if !ok {
+		return "", false
+	}
+	nodeName, ok := strings.CutSuffix(rest, " in cluster "+clusterName)
+	if !ok || nodeName == "" || strings.Contains(nodeName, " ") {
+		return "", false
+	}
+	return nodeName, true
+}
+
+// routeNexthopAddress returns the first InternalIP of the target node in the address family of the destination.
+func routeNexthopAddress(route *cloudprovider.Route, ipv4 bool) (netip.Addr, error) {
+	for _, address := range route.TargetNodeAddresses {
+		if address.Type != v1.NodeInternalIP {
+			continue
+		}
+		ip, err := netip.ParseAddr(address.Address)
+		if err != nil {
+			continue
+		}
+		if ip.Is4() == ipv4 {
+			return ip, nil
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("node %s has no InternalIP in the address family of route destination %s", route.TargetNode, route.DestinationCIDR)
+}
+
+func newIPSubnet(prefix netip.Prefix) *networkingModels.IPSubnet {
+	if prefix.Addr().Is4() {
+		return &networkingModels.IPSubnet{
+			Ipv4: &networkingModels.IPv4Subnet{
+				Ip:           &networkingCommonModels.IPv4Address{Value: ptr.To(prefix.Addr().String())},
+				PrefixLength: ptr.To(prefix.Bits()),
+			},
+		}
+	}
+	return &networkingModels.IPSubnet{
+		Ipv6: &networkingModels.IPv6Subnet{
+			Ip:           &networkingCommonModels.IPv6Address{Value: ptr.To(prefix.Addr().String())},
+			PrefixLength: ptr.To(prefix.Bits()),
+		},
+	}
+}
+
+func newIPAddress(ip netip.Addr) *networkingCommonModels.IPAddress {
+	if ip.Is4() {
+		return &networkingCommonModels.IPAddress{
+			Ipv4: &networkingCommonModels.IPv4Address{Value: ptr.To(ip.String())},
+		}
+	}
+	return &networkingCommonModels.IPAddress{
+		Ipv6: &networkingCommonModels.IPv6Address{Value: ptr.To(ip.String())},
+	}
+}
+
+func ipSubnetString(subnet *networkingModels.IPSubnet) string {
+	switch {
+	case subnet.Ipv4 != nil && subnet.Ipv4.Ip != nil && subnet.Ipv4.Ip.Value != nil && subnet.Ipv4.PrefixLength != nil:
+		return fmt.Sprintf("%s/%d", *subnet.Ipv4.Ip.Value, *subnet.Ipv4.PrefixLength)
+	case subnet.Ipv6 != nil && subnet.Ipv6.Ip != nil && subnet.Ipv6.Ip.Value != nil && subnet.Ipv6.PrefixLength != nil:
+		return fmt.Sprintf("%s/%d", *subnet.Ipv6.Ip.Value, *subnet.Ipv6.PrefixLength)
+	}
+	return ""
+}
+
+func nexthopIPAddress(nexthop *networkingModels.Nexthop) string {
+	if nexthop == nil || nexthop.NexthopIpAddress == nil {
+		return ""
+	}
+	if ipv4 := nexthop.NexthopIpAddress.Ipv4; ipv4 != nil {
+		return ptr.Deref(ipv4.Value, "")
+	}
+	if ipv6 := nexthop.NexthopIpAddress.Ipv6; ipv6 != nil {
+		return ptr.Deref(ipv6.Value, "")
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/google/uuid"
+	networkingModels "github.com/nutanix/ntnx-api-golang-clients/networking-go-client/v4/models/networking/v4/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	cloudprovider "k8s.io/cloud-provider"
+	"k8s.io/utils/ptr"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+var _ = Describe("Test Routes", func() { // nolint:typecheck
+	var (
+		ctx             context.Context
+		mockEnvironment *mock.MockEnvironment
+		routesConfig    *config.Routes
+		routes          *vpcRoutes
+	)
+
+	newRoute := func(nodeName string, cidr string, addresses ...string) *cloudprovider.Route {
+		route := &cloudprovider.Route{
+			TargetNode:      types.NodeName(nodeName),
+			DestinationCIDR: cidr,
+		}
+		for _, address := range addresses {
+			route.TargetNodeAddresses = append(route.TargetNodeAddresses, v1.NodeAddress{Type: v1.NodeInternalIP, Address: address})
+		}
+		return route
+	}
+
+	BeforeEach(func() {
+		var err error
+		ctx = context.Background()
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, fake.NewSimpleClientset())
+		Expect(err).ToNot(HaveOccurred())
+		routesConfig = &config.Routes{VPCUUID: mock.MockVPCUUID}
+		routes, err = newVPCRoutes(&nutanixManager{nutanixClient: mock.CreateMockClient(*mockEnvironment)}, routesConfig)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should fail without config", func() {
+		_, err := newVPCRoutes(&nutanixManager{}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should create routes in the route table of the VPC", func() {
+		route := newRoute(mock.MockVMNamePoweredOn, "10.244.1.0/24", "fd00::1", mock.MockIP)
+		Expect(routes.CreateRoute(ctx, mock.MockCluster, "node-uid", route)).To(Succeed())
+
+		created := mockEnvironment.GetRoutes()
+		Expect(created).To(HaveLen(1))
+		Expect(*created[0].Name).To(Equal("k8s-route-mock-cluster-node-uid"))
+		Expect(*created[0].RouteTableReference).To(Equal(mock.MockRouteTableUUID))
+		Expect(*created[0].VpcReference).To(Equal(mock.MockVPCUUID))
+		Expect(*created[0].RouteType).To(Equal(networkingModels.ROUTETYPE_STATIC))
+		Expect(*created[0].Destination.Ipv4.Ip.Value).To(Equal("10.244.1.0"))
+		Expect(*created[0].Destination.Ipv4.PrefixLength).To(Equal(24))
+		Expect(*created[0].Nexthop.NexthopType).To(Equal(networkingModels.NEXTHOPTYPE_IP_ADDRESS))
+		Expect(*created[0].Nexthop.NexthopIpAddress.Ipv4.Value).To(Equal(mock.MockIP))
+
+		listed, err := routes.ListRoutes(ctx, mock.MockCluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(ConsistOf(&cloudprovider.Route{
+			Name:            *created[0].ExtId,
+			TargetNode:      types.NodeName(mock.MockVMNamePoweredOn),
+			DestinationCIDR: "10.244.1.0/24",
+		}))
+	})
+
+	It("should create IPv6 routes", func() {
+		route := newRoute(mock.MockVMNamePoweredOn, "fd00:10:244:1::/64", mock.MockIP, "fd00::1")
+		Expect(routes.CreateRoute(ctx, mock.MockCluster, "node-uid", route)).To(Succeed())
+
+		listed, err := routes.ListRoutes(ctx, mock.MockCluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(HaveLen(1))
+		Expect(listed[0].DestinationCIDR).To(Equal("fd00:10:244:1::/64"))
+		Expect(*mockEnvironment.GetRoutes()[0].Nexthop.NexthopIpAddress.Ipv6.Value).To(Equal("fd00::1"))
+	})
+
+	It("should fail if the node has no InternalIP in the destination family", func() {
+		route := newRoute(mock.MockVMNamePoweredOn, "fd00:10:244:1::/64", mock.MockIP)
+		Expect(routes.CreateRoute(ctx, mock.MockCluster, "node-uid", route)).ToNot(Succeed())
+		Expect(mockEnvironment.GetRoutes()).To(BeEmpty())
+	})
+
+	It("should only list routes of the cluster", func() {
+		mockEnvironment.AddRoute(&networkingModels.Route{
+			ExtId:               ptr.To(uuid.NewString()),
+			Name:                ptr.To("k8s-route-other-cluster-node-uid"),
+			Description:         ptr.To(routeDescription(mock.MockVMNamePoweredOn, "other-cluster")),
+			RouteTableReference: ptr.To(mock.MockRouteTableUUID),
+			Destination:         newIPSubnet(netip.MustParsePrefix("10.245.1.0/24")),
+		})
+		mockEnvironment.AddRoute(&networkingModels.Route{
+			ExtId:               ptr.To(uuid.NewString()),
+			Name:                ptr.To("default"),
+			RouteTableReference: ptr.To(mock.MockRouteTableUUID),
+			Destination:         newIPSubnet(netip.MustParsePrefix("0.0.0.0/0")),
+		})
+		Expect(routes.CreateRoute(ctx, mock.MockCluster, "node-uid", newRoute(mock.MockVMNamePoweredOn, "10.244.1.0/24", mock.MockIP))).To(Succeed())
+
+		listed, err := routes.ListRoutes(ctx, mock.MockCluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(HaveLen(1))
+		Expect(listed[0].DestinationCIDR).To(Equal("10.244.1.0/24"))
+	})
+
+	It("should report routes without next hop as blackhole", func() {
+		mockEnvironment.AddRoute(&networkingModels.Route{
+			ExtId:               ptr.To(uuid.NewString()),
+			Description:         ptr.To(routeDescription(mock.MockVMNamePoweredOn, mock.MockCluster)),
+			RouteTableReference: ptr.To(mock.MockRouteTableUUID),
+			Destination:         newIPSubnet(netip.MustParsePrefix("10.244.1.0/24")),
+		})
+		listed, err := routes.ListRoutes(ctx, mock.MockCluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(HaveLen(1))
+		Expect(listed[0].Blackhole).To(BeTrue())
+	})
+
+	It("should delete routes", func() {
+		Expect(routes.CreateRoute(ctx, mock.MockCluster, "node-uid", newRoute(mock.MockVMNamePoweredOn, "10.244.1.0/24", mock.MockIP))).To(Succeed())
+		listed, err := routes.ListRoutes(ctx, mock.MockCluster)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(listed).To(HaveLen(1))
+
+		Expect(routes.DeleteRoute(ctx, mock.MockCluster, listed[0])).To(Succeed())
+		Expect(mockEnvironment.GetRoutes()).To(BeEmpty())
+	})
+
+	It("should use the configured route table", func() {
+		routesConfig.RouteTableUUID = "non-existing-route-table"
+		r, err := newVPCRoutes(routes.manager, routesConfig)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = r.ListRoutes(ctx, mock.MockCluster)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail if the VPC has no route table", func() {
+		routesConfig.VPCUUID = "non-existing-vpc"
+		_, err := routes.ListRoutes(ctx, mock.MockCluster)
+		Expect(err).To(HaveOccurred())
+	})
+})
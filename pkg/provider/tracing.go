@@ -0,0 +1,192 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"k8s.io/klog/v2"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+)
+
+const (
+	tracerName = "github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider"
+
+	tracerShutdownTimeout = 5 * time.Second
+)
+
+// Span attributes identifying the Prism entities of a request
+const (
+	nodeNameAttribute            = attribute.Key("k8s.node.name")
+	vmUUIDAttribute              = attribute.Key("nutanix.vm.uuid")
+	clusterUUIDAttribute         = attribute.Key("nutanix.cluster.uuid")
+	hostUUIDAttribute            = attribute.Key("nutanix.host.uuid")
+	categoryUUIDAttribute        = attribute.Key("nutanix.category.uuid")
+	subnetUUIDAttribute          = attribute.Key("nutanix.subnet.uuid")
+	loadBalancerSessionAttribute = attribute.Key("nutanix.load_balancer_session.uuid")
+	routeTableUUIDAttribute      = attribute.Key("nutanix.route_table.uuid")
+	routeUUIDAttribute           = attribute.Key("nutanix.route.uuid")
+	resultAttribute              = attribute.Key("result")
+)
+
+// tracingFlags are the tracing settings given on the command line.
+var tracingFlags struct {
+	exporter string
+	endpoint string
+	filePath string
+}
+
+// AddTracingFlags adds the flags enabling tracing without changing the cloud config. The flags take
+// precedence over the tracing section of the cloud config.
+func AddTracingFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&tracingFlags.exporter, "tracing-exporter", "",
+		"Exporter of the OpenTelemetry traces of the cloud provider: OTLP, Stdout or File. Tracing is disabled if not set in the flags or the cloud config.")
+	fs.StringVar(&tracingFlags.endpoint, "tracing-endpoint", "",
+		"host:port of the OTLP gRPC collector traces are sent to by the OTLP exporter.")
+	fs.StringVar(&tracingFlags.filePath, "tracing-file", "",
+		"File the File exporter appends traces to.")
+}
+
+// tracingConfig merges the tracing flags into the tracing section of the cloud config.
+func tracingConfig(tracing *config.Tracing) (*config.Tracing, error) {
+	if tracingFlags.exporter == "" && tracingFlags.endpoint == "" && tracingFlags.filePath == "" {
+		return tracing, nil
+	}
+	merged := config.Tracing{}
+	if tracing != nil {
+		merged = *tracing
+	}
+	if tracingFlags.exporter != "" {
+		merged.Exporter = config.TracingExporter(tracingFlags.exporter)
+	}
+	if tracingFlags.endpoint != "" {
+		merged.Endpoint = tracingFlags.endpoint
+	}
+	if tracingFlags.filePath != "" {
+		merged.FilePath = tracingFlags.filePath
+	}
+	if err := config.ValidateTracing(&merged); err != nil {
+		return nil, fmt.Errorf("invalid tracing flags: %w", err)
+	}
+	return &merged, nil
+}
+
+// tracerShutdownFunc flushes the spans which were not exported yet and releases the exporter.
+type tracerShutdownFunc func(ctx context.Context) error
+
+// newTracer returns a tracer which does not record anything if tracing is disabled, and the function
+// shutting it down.
+func newTracer(ctx context.Context, tracing *config.Tracing) (trace.Tracer, tracerShutdownFunc, error) {
+	if tracing == nil {
+		return noop.NewTracerProvider().Tracer(tracerName), func(context.Context) error { return nil }, nil
+	}
+
+	var processor sdktrace.SpanProcessor
+	var file *os.File
+	switch tracing.Exporter {
+	case config.OTLPTracingExporter:
+		var opts []otlptracegrpc.Option
+		if tracing.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(tracing.Endpoint))
+		}
+		if tracing.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err := otlptracegrpc.New(ctx, opts...)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		processor = sdktrace.NewBatchSpanProcessor(exporter)
+	case config.StdoutTracingExporter, config.FileTracingExporter:
+		writer := os.Stdout
+		if tracing.Exporter == config.FileTracingExporter {
+			var err error
+			file, err = os.OpenFile(tracing.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to open trace file: %w", err)
+			}
+			writer = file
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(writer))
+		if err != nil {
+			if file != nil {
+				_ = file.Close()
+			}
+			return nil, nil, fmt.Errorf("failed to create %s trace exporter: %w", tracing.Exporter, err)
+		}
+		// Spans are written as they end, so none are lost when the process exits
+		processor = sdktrace.NewSimpleSpanProcessor(exporter)
+	default:
+		return nil, nil, fmt.Errorf("unsupported tracing exporter: %s", tracing.Exporter)
+	}
+
+	samplingRatio := 1.0
+	if tracing.SamplingRatio != nil {
+		samplingRatio = *tracing.SamplingRatio
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(samplingRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(constants.ClientName))),
+	)
+	shutdown := func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			err = errors.Join(err, file.Close())
+		}
+		return err
+	}
+	return provider.Tracer(tracerName), shutdown, nil
+}
+
+// shutdownTracer shuts the tracer of the manager down, which must not be used afterwards.
+func (n *nutanixManager) shutdownTracer() {
+	if n.tracerShutdown == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+	defer cancel()
+	if err := n.tracerShutdown(ctx); err != nil {
+		klog.Errorf("failed to shut down tracer: %v", err) //nolint:typecheck
+	}
+}
+
+// endSpan ends a span with the result of its operation.
+func endSpan(span trace.Span, result string, err error) {
+	span.SetAttributes(resultAttribute.String(result))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2022 Nutanix, Inc
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+//nolint:typecheck // Test file uses ginkgo/gomega which typecheck doesn't understand well
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go4.org/netipx"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/testing/mock"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/interfaces"
+)
+
+// tracedClient returns the mock Prism wrapped like the Prism of nutanixClientEnvironment.
+type tracedClient struct {
+	interfaces.Client
+	tracer trace.Tracer
+}
+
+func (c *tracedClient) Get() (interfaces.Prism, error) {
+	return c.GetForPrismCentral(c.PrismCentrals()[0])
+}
+
+func (c *tracedClient) GetForPrismCentral(name string) (interfaces.Prism, error) {
+	prism, err := c.Client.GetForPrismCentral(name)
+	if err != nil {
+		return nil, err
+	}
+	return newInstrumentedPrism(prism, c.tracer), nil
+}
+
+var _ = Describe("Test tracing", func() { //nolint:typecheck
+	var (
+		ctx             context.Context
+		mockEnvironment *mock.MockEnvironment
+		recorder        *tracetest.SpanRecorder
+		i               instancesV2
+	)
+
+	spanAttributes := func(span sdktrace.ReadOnlySpan) map[attribute.Key]string {
+		attributes := map[attribute.Key]string{}
+		for _, kv := range span.Attributes() {
+			attributes[kv.Key] = kv.Value.Emit()
+		}
+		return attributes
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		kClient := fake.NewSimpleClientset()
+		var err error
+		mockEnvironment, err = mock.CreateMockEnvironment(ctx, kClient)
+		Expect(err).ToNot(HaveOccurred())
+
+		recorder = tracetest.NewSpanRecorder()
+		tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(tracerName)
+		i = instancesV2{
+			nutanixManager: &nutanixManager{
+				config:         config.Config{TopologyDiscovery: config.TopologyDiscovery{Type: config.PrismTopologyDiscoveryType}},
+				client:         kClient,
+				nutanixClient:  &tracedClient{Client: mock.CreateMockClient(*mockEnvironment), tracer: tracer},
+				ignoredNodeIPs: &netipx.IPSet{},
+				tracer:         tracer,
+			},
+		}
+	})
+
+	It("should trace Prism requests as children of the instance operation", func() {
+		node := mockEnvironment.GetNode(mock.MockVMNamePoweredOn)
+		Expect(node).ToNot(BeNil())
+		_, err := i.InstanceExists(ctx, node)
+		Expect(err).ToNot(HaveOccurred())
+
+		spans := recorder.Ended()
+		Expect(len(spans)).To(BeNumerically(">=", 2))
+		operation := spans[len(spans)-1]
+		Expect(operation.Name()).To(Equal("InstanceExists"))
+		Expect(spanAttributes(operation)).To(HaveKeyWithValue(nodeNameAttribute, node.Name))
+		Expect(spanAttributes(operation)).To(HaveKeyWithValue(resultAttribute, "exists"))
+
+		request := spans[0]
+		Expect(request.Name()).To(Equal("Prism.GetVM"))
+		Expect(request.Parent().SpanID()).To(Equal(operation.SpanContext().SpanID()))
+		Expect(spanAttributes(request)).To(HaveKeyWithValue(vmUUIDAttribute, node.Status.NodeInfo.SystemUUID))
+		Expect(spanAttributes(request)).To(HaveKey(clusterUUIDAttribute))
+		Expect(spanAttributes(request)).To(HaveKeyWithValue(resultAttribute, "success"))
+	})
+
+	It("should record failed Prism requests as errors", func() {
+		prism := newInstrumentedPrism(&flakyPrism{failures: 1, err: interfaces.ErrUnavailable}, i.nutanixManager.tracer)
+		_, err := prism.GetVM(ctx, mock.MockVMPoweredOnUUID)
+		Expect(err).To(MatchError(interfaces.ErrUnavailable))
+
+		spans := recorder.Ended()
+		Expect(spans).To(HaveLen(1))
+		Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		Expect(spanAttributes(spans[0])).To(HaveKeyWithValue(resultAttribute, "unavailable"))
+	})
+
+	It("should write spans to a file", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "traces.json")
+		tracer, shutdown, err := newTracer(ctx, &config.Tracing{Exporter: config.FileTracingExporter, FilePath: path})
+		Expect(err).ToNot(HaveOccurred())
+		_, span := tracer.Start(ctx, "InstanceMetadata")
+		endSpan(span, "success", nil)
+
+		traces, err := os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(traces)).To(ContainSubstring(`"Name":"InstanceMetadata"`))
+
+		Expect(shutdown(ctx)).To(Succeed())
+		_, span = tracer.Start(ctx, "InstanceExists")
+		endSpan(span, "success", nil)
+		traces, err = os.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(traces)).ToNot(ContainSubstring(`"Name":"InstanceExists"`))
+	})
+
+	It("should let the flags override the cloud config", func() {
+		DeferCleanup(func() { tracingFlags.exporter, tracingFlags.filePath = "", "" })
+		tracingFlags.exporter, tracingFlags.filePath = string(config.FileTracingExporter), "/tmp/traces.json"
+		tracing, err := tracingConfig(&config.Tracing{Exporter: config.OTLPTracingExporter, Endpoint: "collector:4317"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tracing.Exporter).To(Equal(config.FileTracingExporter))
+		Expect(tracing.FilePath).To(Equal("/tmp/traces.json"))
+		Expect(tracing.Endpoint).To(Equal("collector:4317"))
+
+		tracingFlags.exporter = "Jaeger"
+		_, err = tracingConfig(nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -2,12 +2,24 @@ package provider
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"regexp"
+	"strings"
 	"time"
 
+	"go4.org/netipx"
+	v1 "k8s.io/api/core/v1"
+
 	"github.com/nutanix-cloud-native/cloud-provider-nutanix/internal/constants"
+	"github.com/nutanix-cloud-native/cloud-provider-nutanix/pkg/provider/config"
 )
 
+// maxResourceClusterNameLength bounds the cluster name part of the names of Prism resources
+const maxResourceClusterNameLength = 32
+
+var invalidResourceNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
 // GetCCMNamespace returns the CCM controller pod namespace
 func GetCCMNamespace() (string, error) {
 	ns := os.Getenv(constants.CCMNamespaceKey)
@@ -17,7 +29,60 @@ func GetCCMNamespace() (string, error) {
 	return ns, nil
 }
 
+// getCCMPodReference returns a reference to the CCM controller pod to record events for
+func getCCMPodReference() (*v1.ObjectReference, error) {
+	ns, err := GetCCMNamespace()
+	if err != nil {
+		return nil, err
+	}
+	name := os.Getenv(constants.CCMPodNameKey)
+	if name == "" {
+		return nil, fmt.Errorf("failed to retrieve CCM pod name. Make sure %s env variable is set", constants.CCMPodNameKey)
+	}
+	return &v1.ObjectReference{Kind: "Pod", Namespace: ns, Name: name}, nil
+}
+
+// resourceClusterName sanitizes and truncates the cluster name for use in the names of Prism
+// resources created for the cluster.
+func resourceClusterName(clusterName string) string {
+	name := invalidResourceNameChars.ReplaceAllString(strings.ToLower(clusterName), "-")
+	if len(name) > maxResourceClusterNameLength {
+		name = name[:maxResourceClusterNameLength]
+	}
+	name = strings.Trim(name, "-")
+	if name == "" {
+		name = "kubernetes"
+	}
+	return name
+}
+
 // NoResyncPeriodFunc returns the 0 resync period
 func NoResyncPeriodFunc() time.Duration {
 	return 0
 }
+
+// parseIPSet builds an IP set from a list of single IPs, CIDR prefixes and IP ranges.
+// The field name is only used to produce meaningful error messages.
+func parseIPSet(field string, entries []string) (*netipx.IPSet, error) {
+	builder := netipx.IPSetBuilder{}
+	for _, entry := range entries {
+		ipRange, err := config.ParseIPRange(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s entry %q: %v", field, entry, err)
+		}
+		builder.AddRange(ipRange)
+	}
+
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s IP set: %v", field, err)
+	}
+	return ipSet, nil
+}
+
+func ipFamilyOf(ip netip.Addr) config.IPFamily {
+	if ip.Unmap().Is4() {
+		return config.IPv4IPFamily
+	}
+	return config.IPv6IPFamily
+}